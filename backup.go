@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type BackupEntry struct {
+	// Path is the original location of snapshotted file or directory.
+	Path string `json:"path"`
+	// Name is the snapshot location relative to backup directory,
+	// empty if Path did not exist at the time of snapshot.
+	Name   string    `json:"name,omitempty"`
+	IsSudo bool      `json:"is_sudo,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Backup keeps snapshots of files and directories taken during a run,
+// before they are modified or deleted. Snapshots are listed in manifest.json.
+type Backup struct {
+	dir string
+	th  TaskHelper
+}
+
+func (b *Backup) Dir() string {
+	return b.dir
+}
+
+func (b *Backup) manifestPath() string {
+	return filepath.Join(b.dir, "manifest.json")
+}
+
+// Entries reads backup manifest in the order of snapshots.
+func (b *Backup) Entries() ([]BackupEntry, error) {
+	data, err := os.ReadFile(b.manifestPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %v", err)
+	}
+	var entries []BackupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode backup manifest: %v", err)
+	}
+	return entries, nil
+}
+
+// Snapshot copies path into backup directory and records it in manifest.
+// Only the first snapshot of a path is kept, since it holds the state
+// before the run. Missing path is recorded too, so restore removes it.
+// It is a no-op for nil backup.
+func (b *Backup) Snapshot(path string, isSudo bool) error {
+	if b == nil || len(path) == 0 {
+		return nil
+	}
+	path = filepath.Clean(path)
+
+	entries, err := b.Entries()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(entries, func(e BackupEntry) bool { return e.Path == path }) {
+		return nil
+	}
+	if err := FCreateDir(b.dir); err != nil {
+		return err
+	}
+
+	entry := BackupEntry{Path: path, IsSudo: isSudo, Time: time.Now()}
+	if _, err := os.Lstat(path); err == nil {
+		entry.Name = strconv.Itoa(len(entries)) + "-" + filepath.Base(path)
+		if err := b.th.Copy(path, filepath.Join(b.dir, entry.Name), isSudo); err != nil {
+			return fmt.Errorf("failed to backup %s: %v", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check path stat: %v", err)
+	}
+
+	data, err := json.MarshalIndent(append(entries, entry), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %v", err)
+	}
+	if err := os.WriteFile(b.manifestPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %v", err)
+	}
+	return nil
+}
+
+// FListBackups returns run ids which have backups in root directory.
+func FListBackups(root string) ([]string, error) {
+	dirEntries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+	var runIDs []string
+	for _, e := range dirEntries {
+		if e.IsDir() {
+			runIDs = append(runIDs, e.Name())
+		}
+	}
+	return runIDs, nil
+}
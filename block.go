@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ManagedBlock is content owned by autonvim inside a user file,
+// kept between BEGIN and END marker comments, so it can be
+// replaced or removed without touching the rest of the file.
+type ManagedBlock struct {
+	id      string
+	content string
+	// comment starts marker lines, # is used if empty.
+	comment string
+}
+
+// blockComments are comment prefixes recognized in markers,
+// so blocks can be removed without knowing the file type.
+var blockComments = []string{"#", "--"}
+
+func (b ManagedBlock) Begin() string {
+	return b.marker("BEGIN")
+}
+
+func (b ManagedBlock) End() string {
+	return b.marker("END")
+}
+
+func (b ManagedBlock) marker(kind string) string {
+	comment := b.comment
+	if len(comment) == 0 {
+		comment = "#"
+	}
+	return comment + " " + kind + " autonvim:" + b.id
+}
+
+// isMarker checks if line is a marker of given kind for block id
+// written with any of blockComments.
+func (b ManagedBlock) isMarker(line, kind string) bool {
+	for _, c := range blockComments {
+		if line == (ManagedBlock{id: b.id, comment: c}).marker(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render returns block content wrapped with markers.
+func (b ManagedBlock) Render() string {
+	content := strings.TrimRight(b.content, "\n")
+	return b.Begin() + "\n" + content + "\n" + b.End() + "\n"
+}
+
+// FReplaceBlock replaces block with the same id in text,
+// or appends it to the end if text has no such block.
+// Duplicated blocks left by manual edits are collapsed into one.
+func FReplaceBlock(text string, block ManagedBlock) (string, error) {
+	return replaceBlock(text, block, block.Render())
+}
+
+// FRemoveBlock removes every block with given id from text.
+func FRemoveBlock(text, id string) (string, error) {
+	return replaceBlock(text, ManagedBlock{id: id}, "")
+}
+
+func replaceBlock(text string, block ManagedBlock, rendered string) (string, error) {
+	lines := strings.SplitAfter(text, "\n")
+	var b strings.Builder
+	isInside, isReplaced := false, false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case block.isMarker(trimmed, "BEGIN"):
+			if isInside {
+				return "", fmt.Errorf("nested '%s' marker", block.Begin())
+			}
+			isInside = true
+		case block.isMarker(trimmed, "END"):
+			if !isInside {
+				return "", fmt.Errorf("'%s' marker without '%s'", block.End(), block.Begin())
+			}
+			isInside = false
+			if !isReplaced {
+				b.WriteString(rendered)
+				isReplaced = true
+			}
+		case !isInside:
+			b.WriteString(line)
+		}
+	}
+	if isInside {
+		return "", fmt.Errorf("'%s' marker without '%s'", block.Begin(), block.End())
+	}
+
+	result := b.String()
+	if !isReplaced && len(rendered) > 0 {
+		if len(result) > 0 && !strings.HasSuffix(result, "\n") {
+			result += "\n"
+		}
+		result += rendered
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplaceBlockIsIdempotent(t *testing.T) {
+	user := "export EDITOR=vim\n# BEGIN autonvim:other\nkept\n# END autonvim:other\n"
+	block := ManagedBlock{id: "path", content: "export PATH=/opt/bin:$PATH\n"}
+
+	once, err := FReplaceBlock(user, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := FReplaceBlock(once, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if once != twice {
+		t.Fatalf("second write changed text:\n%q\n%q", once, twice)
+	}
+	if !strings.HasPrefix(once, user) || strings.Count(once, block.Begin()) != 1 {
+		t.Fatalf("expected single block after user content, got %q", once)
+	}
+
+	block.content = "export PATH=/usr/local/go/bin:$PATH"
+	updated, err := FReplaceBlock(twice, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := user + block.Render(); updated != want {
+		t.Fatalf("block was not replaced in place, got %q, want %q", updated, want)
+	}
+}
+
+func TestRemoveBlockKeepsUserContent(t *testing.T) {
+	tests := []string{
+		"",
+		"export EDITOR=vim\n",
+		"alias ll='ls -l'\n\n# comment\n",
+	}
+	block := ManagedBlock{id: "ohmyzsh", content: "source $ZSH/oh-my-zsh.sh"}
+	for _, user := range tests {
+		written, err := FReplaceBlock(user, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		removed, err := FRemoveBlock(written, block.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed != user {
+			t.Errorf("removal changed user content, got %q, want %q", removed, user)
+		}
+	}
+
+	// content around block written in the middle of file is kept too
+	text := "before\n" + block.Render() + "after\n"
+	removed, err := FRemoveBlock(text, block.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != "before\nafter\n" {
+		t.Fatalf("got %q", removed)
+	}
+	if _, err := FRemoveBlock("# BEGIN autonvim:ohmyzsh\nunterminated\n", block.id); err == nil {
+		t.Fatal("expected block without END marker to be rejected")
+	}
+}
+
+func TestWriteBlockTwiceKeepsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".zshrc")
+	writeFile(t, file, "# mine\n")
+
+	var th TaskHelper
+	block := ManagedBlock{id: "path", content: "export PATH=/opt/bin:$PATH"}
+	for range 2 {
+		if err := th.WriteBlock(file, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# mine\n" + block.Render(); string(data) != want {
+		t.Fatalf("got %q, want %q", data, want)
+	}
+	if err := th.RemoveBlock(file, block.id); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only %s, edits left %d files", file, len(entries))
+	}
+	if data, _ := os.ReadFile(file); string(data) != "# mine\n" {
+		t.Fatalf("removal changed user content, got %q", data)
+	}
+}
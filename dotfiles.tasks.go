@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type DotMode string
+
+const (
+	// DotMove moves subpaths out of temporary clone, default mode.
+	DotMove DotMode = "move"
+	// DotLink keeps repository checked out in repoDir
+	// and symlinks subpaths into destination, so edits can be committed.
+	DotLink DotMode = "link"
+)
+
+type DotConflict string
+
+const (
+	// DotPrompt asks what to do with each conflict.
+	DotPrompt DotConflict = ""
+	// DotAdopt moves existing file into repository and links it back,
+	// local version then shows up as repository modification.
+	DotAdopt DotConflict = "adopt"
+	// DotBackup renames existing file with .autonvim.bak suffix.
+	DotBackup DotConflict = "backup"
+	// DotSkip leaves existing file in place.
+	DotSkip DotConflict = "skip"
+)
+
+// runLink deploys subpaths as symlinks into permanent repository checkout.
+func (t *NeovimDotTask) runLink(cfg NeovimDotConfig) error {
+	name := strings.TrimSuffix(filepath.Base(cfg.url), ".git")
+
+	isNewRepo := false
+	if _, err := os.Stat(filepath.Join(cfg.repoDir, ".git")); err == nil {
+		remote, err := t.th.GitRemoteURL(cfg.repoDir)
+		if err != nil {
+			return err
+		}
+		if remote != cfg.url {
+			return fmt.Errorf("%s is a clone of %s, not %s", cfg.repoDir, remote, cfg.url)
+		}
+	} else {
+		if err := FCreateDir(filepath.Dir(cfg.repoDir)); err != nil {
+			return err
+		}
+		if err := t.th.GitClone(cfg.url, cfg.repoDir, GitCloneOptions{submodules: true}, cfg.isSudo); err != nil {
+			return err
+		}
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.repoDir, IsSudo: cfg.isSudo}
+		if err := t.Journal.Record(entry); err != nil {
+			return err
+		}
+		isNewRepo = true
+	}
+
+	// lock moves only with first clone, lock update or dotfiles sync
+	if commit, ok := cfg.lock.Commit(name, cfg.url, ""); ok {
+		if err := t.resetLocked(cfg, commit); err != nil {
+			return err
+		}
+	} else if isNewRepo {
+		if commit, ok := t.th.GitRevParse(cfg.repoDir, "HEAD"); ok {
+			cfg.lock.Set(name, LockEntry{URL: cfg.url, Commit: commit})
+		}
+		if err := cfg.lock.Save(false); err != nil {
+			return err
+		}
+	}
+
+	dstPath := cfg.path.Join()
+	if err := FCreateDir(dstPath); err != nil {
+		return err
+	}
+	for _, subpath := range cfg.subpaths {
+		src := filepath.Join(cfg.repoDir, cfg.repoSubdir, subpath)
+		dst := filepath.Join(dstPath, subpath)
+		if err := t.link(cfg, src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetLocked moves checked out branch to locked commit. Local modifications
+// and local commits are never discarded, they have to be pushed with
+// dotfiles sync first, which moves the lock as well.
+func (t *NeovimDotTask) resetLocked(cfg NeovimDotConfig, commit string) error {
+	if head, ok := t.th.GitRevParse(cfg.repoDir, "HEAD"); ok && head == commit {
+		return nil
+	}
+	changes, err := t.th.GitStatus(cfg.repoDir)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%s has local modifications and is not at locked commit %s, commit them with dotfiles sync --push or discard them", cfg.repoDir, commit)
+	}
+	if _, ok := t.th.GitRevParse(cfg.repoDir, commit); !ok {
+		if err := t.th.GitFetch(cfg.repoDir, cfg.isSudo); err != nil {
+			return err
+		}
+	}
+	ahead, _, err := t.th.GitAheadBehind(cfg.repoDir, commit)
+	if err != nil {
+		return err
+	}
+	if ahead > 0 {
+		return fmt.Errorf("%s has %d commits, which locked commit %s does not have, push them with dotfiles sync --push", cfg.repoDir, ahead, commit)
+	}
+	return t.th.GitReset(cfg.repoDir, commit, cfg.isSudo)
+}
+
+func (t *NeovimDotTask) link(cfg NeovimDotConfig, src, dst string) error {
+	if target, err := os.Readlink(dst); err == nil && target == src {
+		return nil
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		conflict := cfg.onConflict
+		if conflict == DotPrompt {
+			ask := fmt.Sprintf("'%s' already exists and is not linked to '%s'. What would you like to do?", dst, src)
+			conflict = DotConflict(FPromptChoice(ask, []string{string(DotAdopt), string(DotBackup), string(DotSkip)}))
+		}
+
+		switch conflict {
+		case DotSkip:
+			return nil
+		case DotAdopt:
+			if err := t.Journal.Backup().Snapshot(src, cfg.isSudo); err != nil {
+				return err
+			}
+			if err := t.th.DeletePath(src, cfg.isSudo); err != nil {
+				return err
+			}
+			if err := t.th.Move(dst, src, cfg.isSudo); err != nil {
+				return err
+			}
+		case DotBackup:
+			if err := t.Journal.Backup().Snapshot(dst, cfg.isSudo); err != nil {
+				return err
+			}
+			if err := t.th.Move(dst, dst+".autonvim.bak", cfg.isSudo); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown conflict resolution '%s'", conflict)
+		}
+	}
+
+	if _, err := os.Lstat(src); err != nil {
+		return fmt.Errorf("%s is missing in repository", src)
+	}
+	if err := t.th.Symlink(src, dst, cfg.isSudo); err != nil {
+		return err
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dst, IsSudo: cfg.isSudo}
+	return t.Journal.Record(entry)
+}
+
+type DotState string
+
+const (
+	DotLinked   DotState = "linked"
+	DotModified DotState = "modified"
+	DotMissing  DotState = "missing"
+	// DotUnlinked means destination exists, but is not a link into repository.
+	DotUnlinked DotState = "unlinked"
+)
+
+// DotfilesStatusTask prints state of each deployed subpath in link mode.
+type DotfilesStatusTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *DotfilesStatusTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(NeovimDotConfig)
+
+	if cfg.mode != DotLink {
+		return FPrefixError(t.Name, "status is only available in link mode")
+	}
+	if err := t.vh.ValidatePath(cfg.repoDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *DotfilesStatusTask) Run() error {
+	cfg, _ := t.Config.(NeovimDotConfig)
+
+	for _, subpath := range cfg.subpaths {
+		state, err := t.State(cfg, subpath)
+		if err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		fmt.Printf("%-9s %s\n", state, filepath.Join(cfg.path.Join(), subpath))
+	}
+	return nil
+}
+
+// State reports deployment state of a single subpath.
+func (t *DotfilesStatusTask) State(cfg NeovimDotConfig, subpath string) (DotState, error) {
+	repoPath := filepath.Join(cfg.repoSubdir, subpath)
+	src := filepath.Join(cfg.repoDir, repoPath)
+	dst := filepath.Join(cfg.path.Join(), subpath)
+
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		return DotMissing, nil
+	}
+	if target, err := os.Readlink(dst); err != nil || target != src {
+		return DotUnlinked, nil
+	}
+	if _, err := os.Stat(src); err != nil {
+		return DotMissing, nil
+	}
+	changes, err := t.th.GitStatus(cfg.repoDir, repoPath)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) > 0 {
+		return DotModified, nil
+	}
+	return DotLinked, nil
+}
+
+type DotfilesSyncConfig struct {
+	dot NeovimDotConfig
+	// push commits local modifications with message and pushes them.
+	push    bool
+	message string
+}
+
+// DotfilesSyncTask fast-forwards dotfiles checkout to upstream,
+// when there are no local changes, and optionally pushes local changes.
+// Otherwise it shows diff and stops, leaving the repository untouched.
+type DotfilesSyncTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *DotfilesSyncTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(DotfilesSyncConfig)
+
+	if cfg.dot.mode != DotLink {
+		return FPrefixError(t.Name, "sync is only available in link mode")
+	}
+	if err := t.vh.ValidatePath(cfg.dot.repoDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if cfg.push && len(strings.TrimSpace(cfg.message)) == 0 {
+		return FPrefixError(t.Name, "commit message is required to push")
+	}
+	return nil
+}
+
+func (t *DotfilesSyncTask) Run() error {
+	cfg, _ := t.Config.(DotfilesSyncConfig)
+	dir := cfg.dot.repoDir
+
+	branch, err := t.th.GitCurrentBranch(dir)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	upstream := "origin/" + branch
+	if err := t.th.GitFetch(dir, cfg.dot.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	changes, err := t.th.GitStatus(dir)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(changes) > 0 {
+		if !cfg.push {
+			fmt.Println("local modifications:")
+			if err := t.th.GitDiff(dir); err != nil {
+				return FPrefixError(t.Name, err.Error())
+			}
+			return FPrefixError(t.Name, "sync stopped, commit local modifications with --push or discard them")
+		}
+		if err := t.th.GitCommit(dir, cfg.message, cfg.dot.isSudo, "."); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+
+	ahead, behind, err := t.th.GitAheadBehind(dir, upstream)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	switch {
+	case ahead > 0 && behind > 0:
+		fmt.Printf("local and %s have diverged, %d and %d different commits:\n", upstream, ahead, behind)
+		if err := t.th.GitDiff(dir, "HEAD..."+upstream); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		return FPrefixError(t.Name, "sync stopped, merge or rebase manually")
+	case behind > 0:
+		if err := t.th.GitMergeFastForward(dir, upstream, cfg.dot.isSudo); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		fmt.Printf("fast-forwarded %d commits from %s\n", behind, upstream)
+	case ahead > 0 && cfg.push:
+		if err := t.th.GitPush(dir, branch, cfg.dot.isSudo); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		fmt.Printf("pushed %d commits to %s\n", ahead, upstream)
+	case ahead > 0:
+		fmt.Printf("%d local commits are not pushed, use --push\n", ahead)
+	default:
+		fmt.Println("up to date with " + upstream)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(cfg.dot.url), ".git")
+	if commit, ok := t.th.GitRevParse(dir, "HEAD"); ok {
+		cfg.dot.lock.Set(name, LockEntry{URL: cfg.dot.url, Commit: commit})
+	}
+	if err := cfg.dot.lock.Save(true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// syncFixture is a bare repository with two clones of it, a and b.
+type syncFixture struct {
+	th     TaskHelper
+	remote string
+	a, b   string
+}
+
+func newSyncFixture(t *testing.T) syncFixture {
+	t.Helper()
+	r := newGitRemote(t, "dotfiles")
+	f := syncFixture{
+		remote: r.remote,
+		a:      r.work,
+		b:      filepath.Join(filepath.Dir(r.work), "b"),
+	}
+	if err := f.th.GitClone(f.remote, f.b, GitCloneOptions{}, false); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f syncFixture) sync(repoDir string, push bool, message string) error {
+	task := DotfilesSyncTask{
+		BaseTask: BaseTask{
+			Name: "DotfilesSyncTask",
+			Config: DotfilesSyncConfig{
+				dot:     NeovimDotConfig{url: f.remote, mode: DotLink, repoDir: repoDir},
+				push:    push,
+				message: message,
+			},
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+func TestDotfilesSyncPushAndPull(t *testing.T) {
+	f := newSyncFixture(t)
+
+	writeFile(t, filepath.Join(f.b, "init.lua"), "-- edited in b\n")
+	if err := f.sync(f.b, true, "edit init"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if got, want := git(t, f.remote, "rev-parse", "main"), git(t, f.b, "rev-parse", "HEAD"); got != want {
+		t.Fatalf("remote main is %s, want pushed %s", got, want)
+	}
+
+	if err := f.sync(f.a, false, ""); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(f.a, "init.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "-- edited in b\n" {
+		t.Fatalf("init.lua was not fast-forwarded, got %q", data)
+	}
+}
+
+func TestDotfilesSyncStopsOnLocalEdit(t *testing.T) {
+	f := newSyncFixture(t)
+	head := git(t, f.a, "rev-parse", "HEAD")
+
+	writeFile(t, filepath.Join(f.a, "init.lua"), "-- local edit\n")
+	changes, err := f.th.GitStatus(f.a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected one local change, got %v", changes)
+	}
+	err = f.sync(f.a, false, "")
+	if err == nil || !strings.Contains(err.Error(), "local modifications") {
+		t.Fatalf("expected sync to stop on local modifications, got %v", err)
+	}
+	if got := git(t, f.a, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("HEAD moved from %s to %s", head, got)
+	}
+	if got := git(t, f.remote, "rev-parse", "main"); got != head {
+		t.Fatalf("local edit was pushed, remote main is %s", got)
+	}
+}
+
+func TestDotfilesSyncStopsWhenDiverged(t *testing.T) {
+	f := newSyncFixture(t)
+
+	writeFile(t, filepath.Join(f.b, "keymaps.lua"), "-- from b\n")
+	if err := f.sync(f.b, true, "add keymaps"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	writeFile(t, filepath.Join(f.a, "options.lua"), "-- from a\n")
+	if err := f.th.GitCommit(f.a, "add options", false, "."); err != nil {
+		t.Fatal(err)
+	}
+	head := git(t, f.a, "rev-parse", "HEAD")
+
+	if err := f.th.GitFetch(f.a, false); err != nil {
+		t.Fatal(err)
+	}
+	ahead, behind, err := f.th.GitAheadBehind(f.a, "origin/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != 1 || behind != 1 {
+		t.Fatalf("expected 1 ahead and 1 behind, got %d and %d", ahead, behind)
+	}
+	err = f.sync(f.a, true, "sync")
+	if err == nil || !strings.Contains(err.Error(), "merge or rebase") {
+		t.Fatalf("expected sync to stop on diverged history, got %v", err)
+	}
+	if got := git(t, f.a, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("HEAD moved from %s to %s", head, got)
+	}
+}
+
+func TestDotfilesLinkKeepsLockedCommit(t *testing.T) {
+	f := newSyncFixture(t)
+	lock, err := LoadLockfile(filepath.Join(t.TempDir(), "autonvim-lock.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := git(t, f.a, "rev-parse", "HEAD")
+	lock.Set("dotfiles", LockEntry{URL: f.remote, Commit: locked})
+
+	writeFile(t, filepath.Join(f.a, "options.lua"), "-- not pushed\n")
+	if err := f.th.GitCommit(f.a, "add options", false, "."); err != nil {
+		t.Fatal(err)
+	}
+	local := git(t, f.a, "rev-parse", "HEAD")
+	task := NeovimDotTask{BaseTask: BaseTask{Name: "NeovimDotTask"}}
+	cfg := NeovimDotConfig{
+		path:       Path{path: t.TempDir(), subpath: "nvim"},
+		url:        f.remote,
+		subpaths:   []string{"init.lua"},
+		mode:       DotLink,
+		repoDir:    f.a,
+		onConflict: DotSkip,
+		lock:       lock,
+	}
+	if err := task.runLink(cfg); err == nil || !strings.Contains(err.Error(), "dotfiles sync --push") {
+		t.Fatalf("expected unpushed commit to stop reset, got %v", err)
+	}
+	if got := git(t, f.a, "rev-parse", "HEAD"); got != local {
+		t.Fatalf("unpushed commit was discarded, HEAD is %s, want %s", got, local)
+	}
+	if commit, _ := lock.Commit("dotfiles", f.remote, ""); commit != locked {
+		t.Fatalf("lock moved to %s", commit)
+	}
+
+	// lock moved by another machine is fetched and checked out
+	if err := f.th.GitPush(f.a, "main", false); err != nil {
+		t.Fatal(err)
+	}
+	lock.Set("dotfiles", LockEntry{URL: f.remote, Commit: local})
+	cfg.repoDir = f.b
+	if err := task.runLink(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, f.b, "rev-parse", "HEAD"); got != local {
+		t.Fatalf("HEAD is %s, want locked %s", got, local)
+	}
+
+	writeFile(t, filepath.Join(f.b, "init.lua"), "-- local edit\n")
+	lock.Set("dotfiles", LockEntry{URL: f.remote, Commit: locked})
+	if err := task.runLink(cfg); err == nil || !strings.Contains(err.Error(), "local modifications") {
+		t.Fatalf("expected local modifications to stop reset, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// EditorModules are Lua modules generated under lua/autonvim,
+// init.lua requires them in this order.
+var EditorModules = []string{"options", "keymaps", "plugins", "lsp", "format", "colorscheme"}
+
+const editorHeader = "-- Generated by autonvim from editor settings, code outside of this block is kept.\n"
+
+// Keymap is rendered as vim.keymap.set call.
+type Keymap struct {
+	// mode is one or more mode characters, e.g. "n" or "nv".
+	mode string
+	lhs  string
+	rhs  string
+	desc string
+}
+
+// Formatter formats buffers of filetype before they are written.
+// command reads buffer from stdin and prints formatted text,
+// with lsp attached language server is used instead.
+type Formatter struct {
+	filetype string
+	command  []string
+	lsp      bool
+}
+
+// EditorSettings is declarative Neovim configuration,
+// rendered into init.lua and lua/autonvim modules.
+type EditorSettings struct {
+	leader string
+	// options are vim.opt values: bool, int, float64, string or []string.
+	options map[string]any
+	keymaps []Keymap
+	// plugins are Lua modules which are required and set up with defaults.
+	plugins []string
+	// servers are registry names set up with nvim-lspconfig, versions are ignored.
+	servers     []string
+	formatters  []Formatter
+	colorscheme string
+}
+
+// Render returns Lua sources keyed by path relative to Neovim config directory.
+// Maps and servers are sorted, so output is deterministic.
+func (s EditorSettings) Render() (map[string]string, error) {
+	options, err := s.renderOptions()
+	if err != nil {
+		return nil, err
+	}
+	modules := map[string]string{
+		"options":     options,
+		"keymaps":     s.renderKeymaps(),
+		"plugins":     s.renderPlugins(),
+		"lsp":         s.renderLsp(),
+		"format":      s.renderFormat(),
+		"colorscheme": s.renderColorscheme(),
+	}
+
+	files := map[string]string{}
+	var init strings.Builder
+	for _, name := range EditorModules {
+		init.WriteString("require(" + FLuaString("autonvim."+name) + ")\n")
+		files[EditorModulePath(name)] = editorHeader + modules[name]
+	}
+	files["init.lua"] = editorHeader + init.String()
+	return files, nil
+}
+
+// EditorModulePath returns path of generated module relative to config directory.
+func EditorModulePath(name string) string {
+	return filepath.Join("lua", "autonvim", name+".lua")
+}
+
+func (s EditorSettings) renderOptions() (string, error) {
+	var b strings.Builder
+	if len(s.leader) > 0 {
+		b.WriteString("vim.g.mapleader = " + FLuaString(s.leader) + "\n")
+		b.WriteString("vim.g.maplocalleader = " + FLuaString(s.leader) + "\n")
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.options)) {
+		value, err := FLuaValue(s.options[name])
+		if err != nil {
+			return "", fmt.Errorf("option %s: %v", name, err)
+		}
+		b.WriteString("vim.opt." + name + " = " + value + "\n")
+	}
+	return b.String(), nil
+}
+
+func (s EditorSettings) renderKeymaps() string {
+	var b strings.Builder
+	for _, k := range s.keymaps {
+		var modes []string
+		for _, m := range k.mode {
+			modes = append(modes, FLuaString(string(m)))
+		}
+		opts := "{ silent = true }"
+		if len(k.desc) > 0 {
+			opts = "{ silent = true, desc = " + FLuaString(k.desc) + " }"
+		}
+		fmt.Fprintf(&b, "vim.keymap.set({ %s }, %s, %s, %s)\n",
+			strings.Join(modes, ", "), FLuaString(k.lhs), FLuaString(k.rhs), opts)
+	}
+	return b.String()
+}
+
+func (s EditorSettings) renderPlugins() string {
+	if len(s.plugins) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("local function setup(name)\n")
+	b.WriteString("  local ok, plugin = pcall(require, name)\n")
+	b.WriteString("  if ok and type(plugin) == \"table\" and type(plugin.setup) == \"function\" then\n")
+	b.WriteString("    plugin.setup({})\n")
+	b.WriteString("  end\n")
+	b.WriteString("end\n\n")
+	for _, name := range s.plugins {
+		b.WriteString("setup(" + FLuaString(name) + ")\n")
+	}
+	return b.String()
+}
+
+func (s EditorSettings) renderLsp() string {
+	if len(s.servers) == 0 {
+		return ""
+	}
+	var names []string
+	for _, selection := range s.servers {
+		name, _ := FParseServerSelection(selection)
+		names = append(names, name)
+	}
+	var b strings.Builder
+	b.WriteString("local ok, lspconfig = pcall(require, \"lspconfig\")\n")
+	b.WriteString("if ok then\n")
+	for _, name := range slices.Compact(slices.Sorted(slices.Values(names))) {
+		b.WriteString("  lspconfig." + name + ".setup({})\n")
+	}
+	b.WriteString("end\n")
+	return b.String()
+}
+
+func (s EditorSettings) renderFormat() string {
+	if len(s.formatters) == 0 {
+		return ""
+	}
+	formatters := slices.Clone(s.formatters)
+	slices.SortFunc(formatters, func(a, b Formatter) int {
+		return strings.Compare(a.filetype, b.filetype)
+	})
+
+	var b strings.Builder
+	b.WriteString("local formatters = {\n")
+	for _, f := range formatters {
+		value := FLuaString("lsp")
+		if !f.lsp {
+			value, _ = FLuaValue(f.command)
+		}
+		b.WriteString("  [" + FLuaString(f.filetype) + "] = " + value + ",\n")
+	}
+	b.WriteString("}\n\n")
+	b.WriteString(`vim.api.nvim_create_autocmd("BufWritePre", {
+  group = vim.api.nvim_create_augroup("autonvim_format", { clear = true }),
+  callback = function(args)
+    local formatter = formatters[vim.bo[args.buf].filetype]
+    if formatter == nil then
+      return
+    end
+    if formatter == "lsp" then
+      vim.lsp.buf.format({ bufnr = args.buf })
+      return
+    end
+    local lines = vim.api.nvim_buf_get_lines(args.buf, 0, -1, false)
+    local out = vim.fn.systemlist(formatter, lines)
+    if vim.v.shell_error ~= 0 then
+      vim.notify("autonvim: " .. formatter[1] .. " failed to format buffer", vim.log.levels.WARN)
+      return
+    end
+    vim.api.nvim_buf_set_lines(args.buf, 0, -1, false, out)
+  end,
+})
+`)
+	return b.String()
+}
+
+func (s EditorSettings) renderColorscheme() string {
+	if len(s.colorscheme) == 0 {
+		return ""
+	}
+	return "pcall(vim.cmd.colorscheme, " + FLuaString(s.colorscheme) + ")\n"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// editorModes are mode characters accepted by vim.keymap.set.
+const editorModes = "nvxsoilct"
+
+type NeovimEditorConfig struct {
+	// path is Neovim config directory, e.g. ~/.config/nvim.
+	path     string
+	settings EditorSettings
+}
+
+// NeovimEditorTask generates init.lua and lua/autonvim modules from
+// editor settings. Generated code is kept in managed blocks,
+// so code users add around them survives regeneration.
+type NeovimEditorTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *NeovimEditorTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(NeovimEditorConfig)
+
+	if len(cfg.path) == 0 {
+		return FPrefixError(t.Name, "config path is empty")
+	}
+	for name, value := range cfg.settings.options {
+		if len(name) == 0 || strings.ContainsAny(name, " .\t\n") {
+			return FPrefixError(t.Name, fmt.Sprintf("invalid option name '%s'", name))
+		}
+		if _, err := FLuaValue(value); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("option %s: %v", name, err))
+		}
+	}
+	for _, k := range cfg.settings.keymaps {
+		if len(k.mode) == 0 || strings.Trim(k.mode, editorModes) != "" {
+			return FPrefixError(t.Name, fmt.Sprintf("keymap %s has invalid mode '%s'", k.lhs, k.mode))
+		}
+		if len(k.lhs) == 0 || len(k.rhs) == 0 {
+			return FPrefixError(t.Name, "keymap lhs and rhs must not be empty")
+		}
+	}
+	for _, selection := range cfg.settings.servers {
+		name, _ := FParseServerSelection(selection)
+		if _, ok := LanguageServerRegistry[name]; !ok {
+			return FPrefixError(t.Name, fmt.Sprintf("unknown language server '%s'", name))
+		}
+	}
+	var filetypes []string
+	for _, f := range cfg.settings.formatters {
+		if len(f.filetype) == 0 {
+			return FPrefixError(t.Name, "formatter filetype is empty")
+		}
+		if slices.Contains(filetypes, f.filetype) {
+			return FPrefixError(t.Name, fmt.Sprintf("duplicated formatter for %s", f.filetype))
+		}
+		if f.lsp == (len(f.command) > 0) {
+			return FPrefixError(t.Name, fmt.Sprintf("formatter for %s must have either command or lsp", f.filetype))
+		}
+		filetypes = append(filetypes, f.filetype)
+	}
+	return nil
+}
+
+func (t *NeovimEditorTask) Run() error {
+	cfg, _ := t.Config.(NeovimEditorConfig)
+
+	files, err := cfg.settings.Render()
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, name := range append([]string{"init.lua"}, editorModulePaths()...) {
+		file := filepath.Join(cfg.path, name)
+		id := "editor-" + strings.TrimSuffix(filepath.Base(name), ".lua")
+		if err := t.Journal.Backup().Snapshot(file, false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if err := FCreateDir(filepath.Dir(file)); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		block := ManagedBlock{id: id, content: files[name], comment: "--"}
+		if err := t.th.WriteBlock(file, block); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+func editorModulePaths() []string {
+	var paths []string
+	for _, name := range EditorModules {
+		paths = append(paths, EditorModulePath(name))
+	}
+	return paths
+}
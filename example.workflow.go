@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,29 +11,160 @@ import (
 
 const (
 	HomePath string = "/home/alex/"
-	ShrcPath string = "/home/alex/.zshrc"
-
-	OhMyZshURL string = "https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh"
-	GolangURL  string = "https://go.dev/dl/go1.24.1.linux-amd64.tar.gz"
-	NvmURL     string = "https://raw.githubusercontent.com/nvm-sh/nvm/v0.40.2/install.sh"
-	NvimURL    string = "https://github.com/neovim/neovim/releases/download/v0.10.4/nvim-linux-x86_64.tar.gz"
-	NvimLSPURL string = "https://github.com/neovim/nvim-lspconfig"
-	NvimDotURL string = "https://github.com/AlexKhomych/neovim-dot.git"
-
-	NvimPath       string = "export PATH=$PATH:/home/alex/.local/share/nvim-linux-x86_64/bin\n"
-	GolangPath     string = "export PATH=$PATH:/home/alex/.local/share/go/bin:/home/alex/go/bin\n"
-	TypescriptPath string = "export NVM_DIR=\"$HOME/.nvm\"\n[ -s \"$NVM_DIR/nvm.sh\" ] && \\. \"$NVM_DIR/nvm.sh\"  # This loads nvm\n[ -s \"$NVM_DIR/bash_completion\" ] && \\. \"$NVM_DIR/bash_completion\"  # This loads nvm bash_completion\n"
+
+	JournalPath string = "/home/alex/.local/state/autonvim/journal.jsonl"
+	// LockfilePath is relative to autonvim checkout, which commands run from.
+	// The lockfile is committed, so every machine checks out the same commits.
+	LockfilePath string = "autonvim-lock.json"
+	GitCacheDir  string = "/home/alex/.cache/autonvim/git"
+
+	NodeVersion     string = "22.14.0"
+	NodeDownloadURL string = "https://nodejs.org/dist"
+
+	NvimVersion     string = "0.10.4"
+	NvimDownloadURL string = "https://github.com/neovim/neovim/releases/download"
+
+	// PythonStandaloneURL replaces distro python when set, e.g. with
+	// install_only build of python-build-standalone and its PythonStandaloneSHA256.
+	PythonStandaloneURL    string = ""
+	PythonStandaloneSHA256 string = ""
+
+	RustToolchain string = "1.85.0"
+	RustupInitURL string = "https://static.rust-lang.org/rustup/dist/x86_64-unknown-linux-gnu/rustup-init"
+	// RustArchivePath is pre-fetched standalone toolchain, e.g.
+	// rust-1.85.0-x86_64-unknown-linux-gnu.tar.gz, used instead of rustup when set.
+	RustArchivePath string = ""
+
+	// GoVersion is exact version, minor series such as 1.24 or empty for the newest stable.
+	GoVersion     string = "1.24.1"
+	GoDownloadURL string = "https://go.dev/dl"
+	ToolchainRoot string = "/home/alex/.local/share/autonvim/toolchains"
+	NvimPath      string = ToolchainRoot + "/neovim/current/bin/nvim"
+
+	// NvimFromSource builds Neovim at NvimSrcRef instead of
+	// installing release tarball, e.g. on architectures without one.
+	NvimFromSource bool   = false
+	NvimSrcRef     string = "v0.10.4"
+	NvimBuildType  string = "Release"
+	NvimCacheDir   string = "/home/alex/.cache/autonvim/neovim"
+
+	// UseEditorSettings generates Neovim config from EditorConfig
+	// instead of deploying dotfiles repository.
+	UseEditorSettings bool = false
+
+	// OhMyZshRevision is full commit hash of OhMyZshRepoURL, Oh My Zsh has no
+	// release tags. Pick one with git ls-remote OhMyZshRepoURL master.
+	OhMyZshRevision string = ""
+	OhMyZshRepoURL  string = "https://github.com/ohmyzsh/ohmyzsh.git"
+	OhMyZshTheme    string = "robbyrussell"
+	NvimSrcURL      string = "https://github.com/neovim/neovim"
+	NvimLSPURL      string = "https://github.com/neovim/nvim-lspconfig"
+	NvimDotURL      string = "https://github.com/AlexKhomych/neovim-dot.git"
+
+	NerdFontURL    string = "https://github.com/ryanoasis/nerd-fonts/releases/download/v3.3.0/JetBrainsMono.tar.xz"
+	NerdFontFamily string = "JetBrainsMono Nerd Font"
+
+	GithubCLIKeyURL      string = "https://cli.github.com/packages/githubcli-archive-keyring.gpg"
+	GithubCLIFingerprint string = "2C6106201985B60E6C7AC87323F3D4EA75716059"
+	GithubCLIRepoURL     string = "https://cli.github.com/packages"
+	GithubAPIURL         string = "https://api.github.com"
 )
 
 var (
+	NvimEnv = []EnvChange{
+		{kind: EnvPath, value: ToolchainRoot + "/neovim/current/bin"},
+	}
+	// GoTools and NodePackages leave out language servers,
+	// Servers are installed from LanguageServerRegistry only.
+	GoTools = []GoTool{
+		{module: "github.com/go-delve/delve/cmd/dlv", version: "v1.24.1"},
+		{module: "honnef.co/go/tools/cmd/staticcheck", version: "2025.1.1"},
+		{module: "github.com/golangci/golangci-lint/cmd/golangci-lint", version: "v1.64.8"},
+		{module: "mvdan.cc/gofumpt", version: "v0.7.0"},
+	}
+	// NodePackages are installed globally into the node version directory.
+	NodePackages = []NpmPackage{
+		{name: "typescript", version: "5.8.2"},
+		{name: "prettier", version: "3.5.3"},
+		{name: "eslint_d", version: "14.3.0"},
+	}
+
+	LanguageServerEnv = []EnvChange{
+		{kind: EnvPath, value: "$HOME/.local/share/autonvim/lsp/bin"},
+	}
+	Servers = []string{"gopls", "ts_ls"}
+
+	ZshPlugins = []string{"git", "fzf", "zsh-autosuggestions", "zsh-syntax-highlighting"}
+	// ZshExtensions are third party plugins and themes cloned into $ZSH_CUSTOM,
+	// they are enabled with ZshPlugins or OhMyZshTheme as usual.
+	ZshExtensions = []ZshCustom{
+		{name: "zsh-autosuggestions", url: "https://github.com/zsh-users/zsh-autosuggestions", ref: "v0.7.1"},
+		{name: "zsh-syntax-highlighting", url: "https://github.com/zsh-users/zsh-syntax-highlighting", ref: "0.8.0"},
+	}
+
+	// CLITools are release tool registry names, optionally with tag as name@tag.
+	CLITools = []string{"fzf", "lazygit"}
+
+	Plugins = []PluginSpec{
+		{url: NvimLSPURL},
+	}
+
+	Parsers = []ParserSpec{
+		{lang: "go", url: "https://github.com/tree-sitter/tree-sitter-go", revision: "v0.23.4"},
+		{lang: "typescript", url: "https://github.com/tree-sitter/tree-sitter-typescript", revision: "v0.23.2", location: "typescript"},
+		{lang: "tsx", url: "https://github.com/tree-sitter/tree-sitter-typescript", revision: "v0.23.2", location: "tsx"},
+	}
+
+	EditorConfig = EditorSettings{
+		leader: " ",
+		options: map[string]any{
+			"number":         true,
+			"relativenumber": true,
+			"expandtab":      true,
+			"shiftwidth":     4,
+			"tabstop":        4,
+			"signcolumn":     "yes",
+			"clipboard":      "unnamedplus",
+		},
+		keymaps: []Keymap{
+			{mode: "n", lhs: "<leader>w", rhs: "<cmd>write<cr>", desc: "Write buffer"},
+			{mode: "n", lhs: "gd", rhs: "<cmd>lua vim.lsp.buf.definition()<cr>", desc: "Go to definition"},
+			{mode: "n", lhs: "<leader>rn", rhs: "<cmd>lua vim.lsp.buf.rename()<cr>", desc: "Rename symbol"},
+		},
+		servers: Servers,
+		formatters: []Formatter{
+			{filetype: "go", lsp: true},
+			{filetype: "typescript", command: []string{"prettier", "--parser", "typescript"}},
+		},
+		colorscheme: "habamax",
+	}
+
+	PythonPackages = []PipPackage{
+		{name: "basedpyright", version: "1.28.4"},
+		{name: "ruff", version: "0.11.2"},
+		{name: "debugpy", version: "1.8.13"},
+		{name: "pynvim", version: "0.5.2"},
+	}
+	PythonBinaries = []string{"basedpyright", "basedpyright-langserver", "ruff"}
+
+	RustComponents = []string{"rustfmt", "clippy", "rust-analyzer"}
+	RustTools      = []CargoTool{
+		{name: "cargo-edit", version: "0.13.2"},
+		{name: "cargo-nextest", version: "0.9.92"},
+	}
+
+	FontStyles = []string{"Regular", "Bold", "Italic", "BoldItalic"}
+
+	NvimBuildPackages = []string{"ninja-build", "gettext", "cmake", "curl", "build-essential"}
+
 	Packages = map[string]string{
-		"curl":             "",
-		"htop":             "",
-		"vim":              "",
-		"zsh":              "",
-		"git":              "",
-		"build-essentials": "",
-		"ripgrep":          "https://github.com/BurntSushi/ripgrep/releases/download/14.1.0/ripgrep_14.1.0-1_amd64.deb",
+		"curl":            "",
+		"htop":            "",
+		"vim":             "",
+		"zsh":             "",
+		"git":             "",
+		"build-essential": "",
+		"ripgrep":         "https://github.com/BurntSushi/ripgrep/releases/download/14.1.0/ripgrep_14.1.0-1_amd64.deb",
 	}
 )
 
@@ -39,17 +172,148 @@ func ExampleRun() {
 	clear, tmpDir := CreateTempDir()
 	defer clear()
 
-	check(InstallPackages(tmpDir))
-	check(OhMyZsh(tmpDir))
-	check(Neovim(tmpDir))
-	check(NeovimLSP())
-	check(Golang(tmpDir))
-	check(Typescript(tmpDir))
-	check(DotConfig(tmpDir))
+	journal, err := OpenJournal(JournalPath)
+	check(err)
+	lock, err := LoadLockfile(LockfilePath)
+	check(err)
+
+	// gh is only in package index once its repository is added
+	check(GithubCLIRepository(tmpDir, journal.Scope("githubcli")))
+	paths, err := PackagePreflight(tmpDir)
+	check(err)
+	check(InstallPackages(paths, journal.Scope("packages")))
+	check(GithubCLI(journal.Scope("githubcli")))
+	check(CommandLineTools(tmpDir, journal.Scope("cli-tools")))
+	check(OhMyZsh(tmpDir, lock, journal.Scope("ohmyzsh")))
+	if NvimFromSource {
+		check(NeovimFromSource(tmpDir, lock, journal.Scope("neovim")))
+	} else {
+		check(Neovim(tmpDir, journal.Scope("neovim")))
+	}
+	check(NerdFont(tmpDir, journal.Scope("font")))
+	check(NeovimPlugins(lock, journal.Scope("neovim-plugins")))
+	check(Treesitter(tmpDir, journal.Scope("treesitter")))
+	check(Golang(tmpDir, journal.Scope("golang")))
+	check(Node(tmpDir, journal.Scope("node")))
+	check(Python(tmpDir, journal.Scope("python")))
+	check(Rust(tmpDir, journal.Scope("rust")))
+	check(LanguageServers(tmpDir, journal.Scope("language-servers")))
+	if UseEditorSettings {
+		check(Editor(journal.Scope("editor")))
+	} else {
+		check(DotConfig(lock, journal.Scope("dotconfig")))
+	}
+	check(NeovimHealth(tmpDir))
+}
+
+// ExampleUninstall reverts what ExampleRun recorded in the journal.
+// Empty component reverts every component.
+func ExampleUninstall(component string, purgePackages bool) error {
+	journal, err := OpenJournal(JournalPath)
+	if err != nil {
+		return err
+	}
+
+	task := UninstallTask{
+		BaseTask: BaseTask{
+			Name: "UninstallTask",
+			Config: UninstallConfig{
+				component:     component,
+				purgePackages: purgePackages,
+			},
+			Journal: journal,
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
 }
 
-func InstallPackages(tmpDir string) error {
-	install := func(name, url, dname string) error {
+// ExampleLockUpdate bumps locked commits of named sources,
+// or of every source if none are named.
+func ExampleLockUpdate(names []string) error {
+	lock, err := LoadLockfile(LockfilePath)
+	if err != nil {
+		return err
+	}
+
+	task := LockUpdateTask{
+		BaseTask: BaseTask{
+			Name: "LockUpdateTask",
+			Config: LockUpdateConfig{
+				lock:     lock,
+				cacheDir: GitCacheDir,
+				names:    names,
+			},
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+// ExampleLockRollback restores previous lockfile.
+func ExampleLockRollback() error {
+	lock, err := LoadLockfile(LockfilePath)
+	if err != nil {
+		return err
+	}
+
+	task := LockRollbackTask{
+		BaseTask: BaseTask{
+			Name: "LockRollbackTask",
+			Config: LockRollbackConfig{
+				lock: lock,
+			},
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+// ExampleRestore puts back files and directories backed up during given run.
+// Empty run id lists runs which have backups.
+func ExampleRestore(runID string) error {
+	journal, err := OpenJournal(JournalPath)
+	if err != nil {
+		return err
+	}
+
+	if len(runID) == 0 {
+		runIDs, err := FListBackups(journal.BackupRoot())
+		if err != nil {
+			return err
+		}
+		for _, id := range runIDs {
+			fmt.Println(id)
+		}
+		return nil
+	}
+
+	task := RestoreTask{
+		BaseTask: BaseTask{
+			Name: "RestoreTask",
+			Config: RestoreConfig{
+				runID: runID,
+			},
+			Journal: journal,
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+// PackagePreflight downloads .deb packages and checks every package,
+// which the workflow installs, before anything is installed.
+// It returns Packages mapped to their downloaded paths.
+func PackagePreflight(tmpDir string) (map[string]string, error) {
+	download := func(name, url, dname string) (string, error) {
 		downloadConfig := DownloadConfig{
 			url: url,
 			path: Path{
@@ -65,21 +329,77 @@ func InstallPackages(tmpDir string) error {
 		}
 
 		if err := downloadTask.Validate(); err != nil {
-			return err
+			return "", err
 		}
 		if err := downloadTask.Run(); err != nil {
-			return err
+			return "", err
+		}
+		return downloadConfig.path.Join(), nil
+	}
+
+	paths := make(map[string]string, len(Packages))
+	for pkgName, pkgURL := range Packages {
+		if len(pkgURL) == 0 {
+			paths[pkgName] = ""
+			continue
 		}
+		idx := strings.LastIndexByte(pkgURL, '/')
+		if idx == -1 {
+			return nil, fmt.Errorf("not supported url: %s", pkgURL)
+		}
+		path, err := download(pkgName, pkgURL, pkgURL[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		paths[pkgName] = path
+	}
+
+	packages := maps.Clone(paths)
+	names := []string{"gh"}
+	if NvimFromSource {
+		names = append(names, NvimBuildPackages...)
+	}
+	if len(PythonStandaloneURL) == 0 {
+		names = append(names, "python3", "python3-venv")
+	}
+	names = append(names, FServerPackages(Servers)...)
+	for _, name := range names {
+		if _, ok := packages[name]; !ok {
+			packages[name] = ""
+		}
+	}
+
+	preflightTask := PackagePreflightTask{
+		BaseTask: BaseTask{
+			Name: "PackagePreflightTask",
+			Config: PackagePreflightConfig{
+				packages: packages,
+			},
+		},
+	}
+	if err := preflightTask.Validate(); err != nil {
+		return nil, err
+	}
+	if err := preflightTask.Run(); err != nil {
+		return nil, err
+	}
 
+	return paths, nil
+}
+
+// InstallPackages installs packages, which PackagePreflight checked.
+func InstallPackages(paths map[string]string, journal *Journal) error {
+	install := func(name, path string) error {
 		config := InstallPackageConfig{
 			name:   name,
-			path:   downloadConfig.path.Join(),
+			path:   path,
 			isSudo: true,
 		}
 		task := InstallPackageTask{
 			BaseTask: BaseTask{
-				Config: config,
-				Name:   "InstallPackage" + " " + name,
+				Config:  config,
+				Name:    "InstallPackage" + " " + name,
+				Journal: journal,
 			},
 		}
 		if err := task.Validate(); err != nil {
@@ -92,12 +412,8 @@ func InstallPackages(tmpDir string) error {
 		return nil
 	}
 
-	for pkgName, pkgURL := range Packages {
-		idx := strings.LastIndexByte(pkgURL, '/')
-		if len(pkgURL) > 0 && idx == -1 {
-			return fmt.Errorf("not supported url: %s", pkgURL)
-		}
-		if err := install(pkgName, pkgURL, pkgURL[idx:]); err != nil {
+	for pkgName, path := range paths {
+		if err := install(pkgName, path); err != nil {
 			return err
 		}
 	}
@@ -105,19 +421,240 @@ func InstallPackages(tmpDir string) error {
 	return nil
 }
 
-func Neovim(tmpDir string) error {
+func GithubCLIRepository(tmpDir string, journal *Journal) error {
+	repoConfig := AptRepositoryConfig{
+		name:          "githubcli",
+		keyURL:        GithubCLIKeyURL,
+		fingerprint:   GithubCLIFingerprint,
+		uris:          []string{GithubCLIRepoURL},
+		suites:        []string{"stable"},
+		components:    []string{"main"},
+		architectures: []string{"amd64"},
+		tmpDir:        tmpDir,
+		isSudo:        true,
+	}
+	repoTask := AptRepositoryTask{
+		BaseTask: BaseTask{
+			Name:    "AptRepositoryTask githubcli",
+			Config:  repoConfig,
+			Journal: journal,
+		},
+	}
+
+	check(repoTask.Validate())
+	check(repoTask.Run())
+
+	return nil
+}
+
+func GithubCLI(journal *Journal) error {
+	installConfig := InstallPackageConfig{
+		name:   "gh",
+		isSudo: true,
+	}
+	installTask := InstallPackageTask{
+		BaseTask: BaseTask{
+			Name:    "InstallPackage gh",
+			Config:  installConfig,
+			Journal: journal,
+		},
+	}
+
+	check(installTask.Validate())
+	check(installTask.Run())
+
+	return nil
+}
+
+func Neovim(tmpDir string, journal *Journal) error {
+	config := ToolchainConfig{
+		root: ToolchainRoot,
+		spec: ToolchainSpec{
+			name:        "neovim",
+			version:     NvimVersion,
+			url:         NvimDownloadURL + "/v{version}/nvim-linux-x86_64.tar.gz",
+			checksumURL: NvimDownloadURL + "/v{version}/shasum.txt",
+			strip:       1,
+			binDir:      "bin",
+			probe:       []string{"nvim", "--version"},
+		},
+		tmpDir: tmpDir,
+		shrc: ShrcConfig{
+			homePath: HomePath,
+			id:       "neovim",
+		},
+	}
+
+	task := ToolchainTask{
+		BaseTask: BaseTask{
+			Name:    "ToolchainTask neovim",
+			Config:  config,
+			Journal: journal,
+		},
+	}
+
+	check(task.Validate())
+	check(task.Run())
+
+	return nil
+}
+
+func NeovimFromSource(tmpDir string, lock *Lockfile, journal *Journal) error {
+	for _, pkgName := range NvimBuildPackages {
+		task := InstallPackageTask{
+			BaseTask: BaseTask{
+				Name:    "InstallPackage" + " " + pkgName,
+				Config:  InstallPackageConfig{name: pkgName, isSudo: true},
+				Journal: journal,
+			},
+		}
+		check(task.Validate())
+		check(task.Run())
+	}
+
+	buildConfig := BuildNeovimConfig{
+		root: ToolchainRoot,
+		shrc: ShrcConfig{
+			homePath: HomePath,
+			id:       "neovim",
+			env:      NvimEnv,
+		},
+		url:       NvimSrcURL,
+		ref:       NvimSrcRef,
+		buildType: NvimBuildType,
+		cacheDir:  NvimCacheDir,
+		tmpDir:    tmpDir,
+		lock:      lock,
+		isSudo:    false,
+	}
+
+	buildTask := BuildNeovimTask{
+		BaseTask: BaseTask{
+			Name:    "BuildNeovimTask",
+			Config:  buildConfig,
+			Journal: journal,
+		},
+	}
+
+	check(buildTask.Validate())
+	check(buildTask.Run())
+
+	return nil
+}
+
+// DotfilesConfig is shared by DotConfig step and dotfiles commands.
+func DotfilesConfig(lock *Lockfile) NeovimDotConfig {
+	return NeovimDotConfig{
+		path: Path{
+			path:    filepath.Join(HomePath, ".config"),
+			subpath: "nvim",
+		},
+		url:        NvimDotURL,
+		repoSubdir: "nvim",
+		subpaths:   []string{"init.lua", "lua"},
+		mode:       DotLink,
+		repoDir:    filepath.Join(HomePath, ".local/share/autonvim/dotfiles/neovim-dot"),
+		onConflict: DotPrompt,
+		lock:       lock,
+		isSudo:     false,
+	}
+}
+
+// Editor is an alternative to DotConfig for users who
+// prefer declarative settings over maintaining Lua.
+func Editor(journal *Journal) error {
+	config := NeovimEditorConfig{
+		path:     filepath.Join(HomePath, ".config/nvim"),
+		settings: EditorConfig,
+	}
+
+	task := NeovimEditorTask{
+		BaseTask: BaseTask{
+			Name:    "NeovimEditorTask",
+			Config:  config,
+			Journal: journal,
+		},
+	}
+
+	check(task.Validate())
+	check(task.Run())
+
+	return nil
+}
+
+func DotConfig(lock *Lockfile, journal *Journal) error {
+	config := DotfilesConfig(lock)
+
+	task := NeovimDotTask{
+		BaseTask: BaseTask{
+			Name:    "NeovimDotTask",
+			Config:  config,
+			Journal: journal,
+		},
+	}
+
+	check(task.Validate())
+	check(task.Run())
+
+	return nil
+}
+
+// ExampleDotfilesSync pulls upstream dotfiles changes
+// and with push commits and pushes local changes.
+func ExampleDotfilesSync(push bool, message string) error {
+	lock, err := LoadLockfile(LockfilePath)
+	if err != nil {
+		return err
+	}
+
+	task := DotfilesSyncTask{
+		BaseTask: BaseTask{
+			Name: "DotfilesSyncTask",
+			Config: DotfilesSyncConfig{
+				dot:     DotfilesConfig(lock),
+				push:    push,
+				message: message,
+			},
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+// ExampleDotfilesStatus prints which dotfiles are linked, modified or missing.
+func ExampleDotfilesStatus() error {
+	lock, err := LoadLockfile(LockfilePath)
+	if err != nil {
+		return err
+	}
+
+	task := DotfilesStatusTask{
+		BaseTask: BaseTask{
+			Name:   "DotfilesStatusTask",
+			Config: DotfilesConfig(lock),
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+func NerdFont(tmpDir string, journal *Journal) error {
 	downloadConfig := DownloadConfig{
 		path: Path{
 			path:    tmpDir,
-			subpath: "nvim-linux-x86_64.tar.gz",
+			subpath: filepath.Base(NerdFontURL),
 		},
-		url:    NvimURL,
+		url:    NerdFontURL,
 		isSudo: false,
 	}
 
 	downloadTask := DownloadTask{
 		BaseTask: BaseTask{
-			Name:   "DownloadTask Neovim",
+			Name:   "DownloadTask NerdFont",
 			Config: downloadConfig,
 		},
 	}
@@ -125,79 +662,129 @@ func Neovim(tmpDir string) error {
 	check(downloadTask.Validate())
 	check(downloadTask.Run())
 
-	installConfig := InstallNeovimConfig{
+	fontConfig := FontConfig{
 		path: Path{
-			path:    filepath.Join(HomePath, ".local/share"),
-			subpath: "nvim-linux-x86_64",
+			path:    filepath.Join(HomePath, ".local/share/fonts"),
+			subpath: NerdFontFamily,
 		},
-		shrc: ShrcConfig{
-			path:    ShrcPath,
-			content: NvimPath,
+		family:      NerdFontFamily,
+		styles:      FontStyles,
+		archivePath: downloadConfig.path.Join(),
+		tmpDir:      tmpDir,
+	}
+
+	fontTask := FontTask{
+		BaseTask: BaseTask{
+			Name:    "FontTask",
+			Config:  fontConfig,
+			Journal: journal,
 		},
-		tarPath: downloadConfig.path.Join(),
-		isSudo:  false,
 	}
 
-	installTask := InstallNeovimTask{
+	check(fontTask.Validate())
+	check(fontTask.Run())
+
+	return nil
+}
+
+// ExampleFontCheck reports whether NerdFontFamily is known to fontconfig.
+func ExampleFontCheck() error {
+	task := FontTask{
 		BaseTask: BaseTask{
-			Name:   "InstallNeovimTask",
-			Config: installConfig,
+			Name:   "FontTask",
+			Config: FontConfig{family: NerdFontFamily, check: true},
 		},
 	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
 
-	opts := OverwriteOptions{
-		path:   installConfig.path,
-		isSudo: false,
+func NeovimPlugins(lock *Lockfile, journal *Journal) error {
+	config := NeovimPluginConfig{
+		path:     filepath.Join(HomePath, ".config/nvim/pack/autonvim"),
+		plugins:  Plugins,
+		nvimPath: NvimPath,
+		lock:     lock,
+		// nvim-lspconfig was cloned there before it became a declared plugin
+		legacyPaths: []string{filepath.Join(HomePath, ".config/nvim/pack/nvim/start/nvim-lspconfig")},
 	}
 
-	isSkip := HandleOverwrite(opts)
-	if isSkip {
-		return nil
+	task := NeovimPluginTask{
+		BaseTask: BaseTask{
+			Name:    "NeovimPluginTask",
+			Config:  config,
+			Journal: journal,
+		},
 	}
 
-	check(installTask.Validate())
-	check(installTask.Run())
+	check(task.Validate())
+	check(task.Run())
 
 	return nil
 }
 
-func DotConfig(tmpDir string) error {
-	tmpDir = filepath.Join(tmpDir, "neovim-dot")
-	if err := FCreateDir(tmpDir); err != nil {
-		return err
-	}
-	config := NeovimDotConfig{
-		path: Path{
-			path:    filepath.Join(HomePath, ".config"),
-			subpath: "nvim",
+func CommandLineTools(tmpDir string, journal *Journal) error {
+	config := ReleaseToolConfig{
+		apiURL: GithubAPIURL,
+		tools:  CLITools,
+		binDir: filepath.Join(HomePath, ".local/bin"),
+		manDir: filepath.Join(HomePath, ".local/share/man"),
+		completionDirs: map[Shell]string{
+			ShellBash: filepath.Join(HomePath, ".local/share/bash-completion/completions"),
+			ShellZsh:  filepath.Join(HomePath, ".local/share/zsh/site-functions"),
+			ShellFish: filepath.Join(HomePath, ".config/fish/completions"),
+		},
+		tmpDir: tmpDir,
+		shrc: ShrcConfig{
+			homePath: HomePath,
+			id:       "cli-tools",
 		},
-		tmpDir:   tmpDir,
-		url:      NvimDotURL,
-		subpaths: []string{"init.lua", "lua"},
-		isSudo:   false,
 	}
 
-	task := NeovimDotTask{
+	task := ReleaseToolTask{
 		BaseTask: BaseTask{
-			Name:   "NeovimDotTask",
-			Config: config,
+			Name:    "ReleaseToolTask",
+			Config:  config,
+			Journal: journal,
 		},
 	}
 
-	for _, subpath := range config.subpaths {
-		path := Path{
-			path:    config.path.Join(),
-			subpath: subpath,
-		}
-		opts := OverwriteOptions{
-			path:   path,
-			isSudo: false,
-		}
+	check(task.Validate())
+	check(task.Run())
 
-		isSkip := HandleOverwrite(opts)
-		if isSkip {
-			continue
-		}
+	return nil
+}
+
+func LanguageServers(tmpDir string, journal *Journal) error {
+	// generated editor settings already set servers up
+	lspconfigPath := filepath.Join(HomePath, ".config/nvim/plugin/autonvim-lsp.lua")
+	if UseEditorSettings {
+		lspconfigPath = ""
+	}
+	config := LanguageServerConfig{
+		path:          filepath.Join(HomePath, ".local/share/autonvim/lsp"),
+		servers:       Servers,
+		apiURL:        GithubAPIURL,
+		tmpDir:        tmpDir,
+		goPath:        filepath.Join(ToolchainRoot, "go/current/bin/go"),
+		nodeBinDir:    filepath.Join(ToolchainRoot, "node/current/bin"),
+		lspconfigPath: lspconfigPath,
+		shrc: ShrcConfig{
+			homePath: HomePath,
+			id:       "language-servers",
+			env:      LanguageServerEnv,
+		},
+		isSudo: true,
+	}
+
+	task := LanguageServerTask{
+		BaseTask: BaseTask{
+			Name:    "LanguageServerTask",
+			Config:  config,
+			Journal: journal,
+		},
 	}
 
 	check(task.Validate())
@@ -206,31 +793,42 @@ func DotConfig(tmpDir string) error {
 	return nil
 }
 
-func NeovimLSP() error {
-	config := NeovimLSPConfig{
-		path: Path{
-			path:    filepath.Join(HomePath, ".config/nvim/pack/nvim/start"),
-			subpath: "nvim-lspconfig",
-		},
-		url:    NvimLSPURL,
-		isSudo: false,
+func Treesitter(tmpDir string, journal *Journal) error {
+	config := TreesitterConfig{
+		path:     filepath.Join(HomePath, ".local/share/nvim/site/parser"),
+		parsers:  Parsers,
+		tmpDir:   tmpDir,
+		compiler: "cc",
+		nvimPath: NvimPath,
 	}
 
-	task := NeovimLSPTask{
+	task := TreesitterTask{
 		BaseTask: BaseTask{
-			Name:   "NeovimLSPTask",
-			Config: config,
+			Name:    "TreesitterTask",
+			Config:  config,
+			Journal: journal,
 		},
 	}
 
-	opts := OverwriteOptions{
-		path:   config.path,
-		isSudo: false,
+	check(task.Validate())
+	check(task.Run())
+
+	return nil
+}
+
+func NeovimHealth(tmpDir string) error {
+	config := NeovimHealthConfig{
+		nvimPath: NvimPath,
+		tmpDir:   tmpDir,
+		failOn:   HealthError,
+		ignore:   []string{"provider.perl", "provider.ruby"},
 	}
 
-	isSkip := HandleOverwrite(opts)
-	if isSkip {
-		return nil
+	task := NeovimHealthTask{
+		BaseTask: BaseTask{
+			Name:   "NeovimHealthTask",
+			Config: config,
+		},
 	}
 
 	check(task.Validate())
@@ -239,7 +837,7 @@ func NeovimLSP() error {
 	return nil
 }
 
-func OhMyZsh(tmpDir string) error {
+func OhMyZsh(tmpDir string, lock *Lockfile, journal *Journal) error {
 	config := OhMyZshConfig{
 		tmpDir: tmpDir,
 		path: Path{
@@ -247,24 +845,27 @@ func OhMyZsh(tmpDir string) error {
 			subpath: ".oh-my-zsh",
 		},
 		username: "alex",
-		url:      OhMyZshURL,
+		repoURL:  OhMyZshRepoURL,
+		revision: OhMyZshRevision,
+		theme:    OhMyZshTheme,
+		plugins:  ZshPlugins,
+		custom:   ZshExtensions,
+		fpath:    []string{filepath.Join(HomePath, ".local/share/zsh/site-functions")},
+		lock:     lock,
 		isSudo:   false,
 	}
 
 	task := OhMyZshTask{
 		BaseTask: BaseTask{
-			Name:   "OhMyZshTask",
-			Config: config,
+			Name:    "OhMyZshTask",
+			Config:  config,
+			Journal: journal,
 		},
 	}
 
-	opts := OverwriteOptions{
-		path:   config.path,
-		isSudo: false,
-	}
-
-	isSkip := HandleOverwrite(opts)
-	if isSkip {
+	// there is no safe default revision, Oh My Zsh is skipped until one is pinned
+	if len(OhMyZshRevision) == 0 {
+		slog.Warn("OhMyZshRevision is not set, skipping Oh My Zsh")
 		return nil
 	}
 
@@ -274,121 +875,161 @@ func OhMyZsh(tmpDir string) error {
 	return nil
 }
 
-func Golang(tmpDir string) error {
-	downloadConfig := DownloadConfig{
-		path: Path{
-			path:    tmpDir,
-			subpath: "go1.24.1.linux-amd64.tar.gz",
+func Golang(tmpDir string, journal *Journal) error {
+	config := GoToolchainConfig{
+		root:    ToolchainRoot,
+		version: GoVersion,
+		baseURL: GoDownloadURL,
+		goos:    "linux",
+		goarch:  "amd64",
+		goPath:  filepath.Join(HomePath, "go"),
+		goBin:   filepath.Join(HomePath, "go/bin"),
+		tools:   GoTools,
+		tmpDir:  tmpDir,
+		shrc: ShrcConfig{
+			homePath: HomePath,
+			id:       "golang",
 		},
-		url:    GolangURL,
-		isSudo: false,
 	}
 
-	downloadTask := DownloadTask{
+	task := GoToolchainTask{
 		BaseTask: BaseTask{
-			Name:   "DownloadTask Golang",
-			Config: downloadConfig,
+			Name:    "GoToolchainTask",
+			Config:  config,
+			Journal: journal,
 		},
 	}
 
-	check(downloadTask.Validate())
-	check(downloadTask.Run())
+	check(task.Validate())
+	check(task.Run())
 
-	installConfig := InstallGolangConfig{
-		path: Path{
-			path:    filepath.Join(HomePath, ".local/share"),
-			subpath: "go",
+	return nil
+}
+
+func Node(tmpDir string, journal *Journal) error {
+	npmInstall, err := FNpmInstallCommand(NodePackages)
+	check(err)
+	var postInstall []string
+	if len(npmInstall) > 0 {
+		postInstall = append(postInstall, npmInstall)
+	}
+
+	config := ToolchainConfig{
+		root: ToolchainRoot,
+		spec: ToolchainSpec{
+			name:        "node",
+			version:     NodeVersion,
+			url:         NodeDownloadURL + "/v{version}/node-v{version}-linux-x64.tar.gz",
+			checksumURL: NodeDownloadURL + "/v{version}/SHASUMS256.txt",
+			strip:       1,
+			binDir:      "bin",
+			postInstall: postInstall,
+			probe:       []string{"node", "--version"},
 		},
-		tarPath: filepath.Join(tmpDir, "go1.24.1.linux-amd64.tar.gz"),
+		tmpDir: tmpDir,
 		shrc: ShrcConfig{
-			path:    ShrcPath,
-			content: GolangPath,
+			homePath: HomePath,
+			id:       "node",
 		},
-		isSudo: false,
 	}
 
-	installTask := InstallGolangTask{
+	task := ToolchainTask{
 		BaseTask: BaseTask{
-			Name:   "InstallGolangTask",
-			Config: installConfig,
+			Name:    "ToolchainTask node",
+			Config:  config,
+			Journal: journal,
 		},
 	}
 
-	opts := OverwriteOptions{
-		path:   installConfig.path,
-		isSudo: false,
-	}
-
-	isSkip := HandleOverwrite(opts)
-	if isSkip {
-		return nil
-	}
-
-	check(installTask.Validate())
-	check(installTask.Run())
+	check(task.Validate())
+	check(task.Run())
 
 	return nil
 }
 
-func Typescript(tmpDir string) error {
-	downloadConfig := DownloadConfig{
-		path: Path{
-			path:    tmpDir,
-			subpath: "nvm_install.sh",
+func Python(tmpDir string, journal *Journal) error {
+	if len(PythonStandaloneURL) == 0 {
+		for _, pkgName := range []string{"python3", "python3-venv"} {
+			task := InstallPackageTask{
+				BaseTask: BaseTask{
+					Name:    "InstallPackage" + " " + pkgName,
+					Config:  InstallPackageConfig{name: pkgName, isSudo: true},
+					Journal: journal,
+				},
+			}
+			check(task.Validate())
+			check(task.Run())
+		}
+	}
+
+	config := PythonToolchainConfig{
+		interpreter:      "/usr/bin/python3",
+		root:             ToolchainRoot,
+		standaloneURL:    PythonStandaloneURL,
+		standaloneSHA256: PythonStandaloneSHA256,
+		venvDir:          filepath.Join(HomePath, ".local/share/autonvim/python/venv"),
+		packages:         PythonPackages,
+		binaries:         PythonBinaries,
+		binDir:           filepath.Join(HomePath, ".local/share/autonvim/python/bin"),
+		providerPath:     filepath.Join(HomePath, ".config/nvim/plugin/autonvim-python.lua"),
+		tmpDir:           tmpDir,
+		shrc: ShrcConfig{
+			homePath: HomePath,
+			id:       "python",
 		},
-		url:    NvmURL,
-		isSudo: false,
 	}
 
-	downloadTask := DownloadTask{
+	task := PythonToolchainTask{
 		BaseTask: BaseTask{
-			Name:   "DownloadTask Typescript",
-			Config: downloadConfig,
+			Name:    "PythonToolchainTask",
+			Config:  config,
+			Journal: journal,
 		},
 	}
 
-	check(downloadTask.Validate())
-	check(downloadTask.Run())
+	check(task.Validate())
+	check(task.Run())
 
-	installConfig := InstallTypescriptConfig{
-		version:        "22.14.0",
-		installNVMPath: downloadConfig.path.Join(),
-		homePath:       HomePath,
+	return nil
+}
+
+func Rust(tmpDir string, journal *Journal) error {
+	config := RustToolchainConfig{
+		toolchain:   RustToolchain,
+		components:  RustComponents,
+		cargoHome:   filepath.Join(HomePath, ".cargo"),
+		rustupHome:  filepath.Join(HomePath, ".rustup"),
+		archivePath: RustArchivePath,
+		root:        ToolchainRoot,
+		tools:       RustTools,
+		tmpDir:      tmpDir,
 		shrc: ShrcConfig{
-			path:    ShrcPath,
-			content: TypescriptPath,
+			homePath: HomePath,
+			id:       "rust",
 		},
-		isSudo: false,
 	}
-
-	installTask := InstallTypescriptTask{
-		BaseTask: BaseTask{
-			Name:   "InstallTypescriptTask",
-			Config: installConfig,
-		},
+	if len(RustArchivePath) == 0 {
+		config.rustupInitURL = RustupInitURL
 	}
 
-	opts := OverwriteOptions{
-		path: Path{
-			path:    HomePath,
-			subpath: filepath.Join(".nvm/versions/node/v" + installConfig.version),
+	task := RustToolchainTask{
+		BaseTask: BaseTask{
+			Name:    "RustToolchainTask",
+			Config:  config,
+			Journal: journal,
 		},
-		isSudo: false,
-	}
-	isSkip := HandleOverwrite(opts)
-	if isSkip {
-		return nil
 	}
 
-	check(installTask.Validate())
-	check(installTask.Run())
+	check(task.Validate())
+	check(task.Run())
 
 	return nil
 }
 
 type OverwriteOptions struct {
-	path   Path
-	isSudo bool
+	path    Path
+	isSudo  bool
+	journal *Journal
 }
 
 func HandleOverwrite(o OverwriteOptions) bool {
@@ -404,8 +1045,9 @@ func HandleOverwrite(o OverwriteOptions) bool {
 		}
 		deleteTask := DeletePathTask{
 			BaseTask: BaseTask{
-				Name:   "DeletePathTask",
-				Config: deleteConfig,
+				Name:    "DeletePathTask",
+				Config:  deleteConfig,
+				Journal: o.journal,
 			},
 		}
 		check(deleteTask.Validate())
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FontExtensions are font file extensions picked from archives.
+var FontExtensions = []string{".ttf", ".otf"}
+
+type FontConfig struct {
+	// path is fonts directory, subpath is a family directory inside of it.
+	path Path
+	// family is fontconfig family name, e.g. "JetBrainsMono Nerd Font Mono".
+	// Archive files are matched by family without spaces,
+	// e.g. JetBrainsMonoNerdFontMono-Bold.ttf.
+	family string
+	// styles are file name suffixes, e.g. Regular, Bold or BoldItalic.
+	styles      []string
+	archivePath string
+	tmpDir      string
+	// check only reports whether family is known to fontconfig.
+	check bool
+}
+
+// FontTask installs selected styles of a font family from archive
+// and refreshes fontconfig cache.
+type FontTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *FontTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(FontConfig)
+
+	if len(cfg.family) == 0 {
+		return FPrefixError(t.Name, "font family is empty")
+	}
+	if cfg.check {
+		return nil
+	}
+	if len(cfg.path.path) == 0 || len(cfg.path.subpath) == 0 {
+		return FPrefixError(t.Name, "fonts directory and family subpath are required")
+	}
+	if len(cfg.styles) == 0 {
+		return FPrefixError(t.Name, "no font styles selected")
+	}
+	if err := t.vh.ValidatePath(cfg.archivePath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *FontTask) Run() error {
+	cfg, _ := t.Config.(FontConfig)
+
+	if cfg.check {
+		isKnown, err := t.isKnown(cfg.family)
+		if err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if isKnown {
+			fmt.Printf("%s: installed\n", cfg.family)
+		} else {
+			fmt.Printf("%s: not known to fontconfig\n", cfg.family)
+		}
+		return nil
+	}
+
+	extractDir := filepath.Join(cfg.tmpDir, "font-"+strings.ReplaceAll(cfg.family, " ", ""))
+	if err := FCreateDir(extractDir); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.th.ExtractArchive(cfg.archivePath, extractDir, 0, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	files, err := FFindFontFiles(extractDir, cfg.family, cfg.styles)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(files) != len(cfg.styles) {
+		return FPrefixError(t.Name, fmt.Sprintf("archive has %d of %d selected styles of %s", len(files), len(cfg.styles), cfg.family))
+	}
+
+	// family directory is replaced, so deselected styles are removed
+	dstPath := cfg.path.Join()
+	if err := t.Journal.Backup().Snapshot(dstPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.th.DeletePath(dstPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := FCreateDir(dstPath); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		if err := t.th.Copy(file, filepath.Join(dstPath, filepath.Base(file)), false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dstPath}
+	if err := t.Journal.Record(entry); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	if err := t.th.RefreshFontCache(dstPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	isKnown, err := t.isKnown(cfg.family)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if !isKnown {
+		return FPrefixError(t.Name, fmt.Sprintf("%s is installed in %s, but not known to fontconfig", cfg.family, dstPath))
+	}
+	slog.Info("font installed", "task_name", t.Name, "family", cfg.family, "styles", cfg.styles)
+	return nil
+}
+
+func (t *FontTask) isKnown(family string) (bool, error) {
+	families, err := t.th.FontFamilies()
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(families, family), nil
+}
+
+// FFindFontFiles returns font files of family in dir, one per style.
+// Family is matched without spaces, so Nerd Font variants
+// such as Mono or Propo are told apart.
+func FFindFontFiles(dir, family string, styles []string) ([]string, error) {
+	prefix := strings.ReplaceAll(family, " ", "")
+	found := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(d.Name())
+		if !slices.Contains(FontExtensions, ext) {
+			return nil
+		}
+		name, style, ok := strings.Cut(strings.TrimSuffix(d.Name(), ext), "-")
+		if !ok || name != prefix || !slices.Contains(styles, style) {
+			return nil
+		}
+		// prefer first extension in FontExtensions when archive has both
+		if prev, ok := found[style]; ok && slices.Index(FontExtensions, filepath.Ext(prev)) <= slices.Index(FontExtensions, ext) {
+			return nil
+		}
+		found[style] = path
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search font files: %v", err)
+	}
+
+	var files []string
+	for _, style := range styles {
+		if path, ok := found[style]; ok {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -34,6 +36,49 @@ func FRunCommand(cmd string, args []string, useSudo bool) (int, error) {
 	return 0, nil
 }
 
+// FRunCommandOutput is similar to FRunCommand,
+// but captures stdout instead of printing it and returns it as string.
+// Stderr is still forwarded to the terminal.
+func FRunCommandOutput(cmd string, args []string, useSudo bool) (string, int, error) {
+	if useSudo {
+		args = append([]string{cmd}, args...)
+		cmd = "sudo"
+	}
+
+	var stdout bytes.Buffer
+	command := exec.Command(cmd, args...)
+	command.Stdout = &stdout
+	command.Stderr = os.Stderr
+	if err := command.Run(); err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			statusCode := exitError.ExitCode()
+			return stdout.String(), statusCode, fmt.Errorf("command failed to execute with status code %d: %v", statusCode, err)
+		}
+		return "", -1, fmt.Errorf("failed to run %s: %v", cmd, err)
+	}
+	return stdout.String(), 0, nil
+}
+
+// FRunCommandCombined is similar to FRunCommandOutput,
+// but captures both stdout and stderr into returned string.
+func FRunCommandCombined(cmd string, args []string, useSudo bool) (string, int, error) {
+	if useSudo {
+		args = append([]string{cmd}, args...)
+		cmd = "sudo"
+	}
+
+	command := exec.Command(cmd, args...)
+	out, err := command.CombinedOutput()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			statusCode := exitError.ExitCode()
+			return string(out), statusCode, fmt.Errorf("command failed to execute with status code %d: %v", statusCode, err)
+		}
+		return "", -1, fmt.Errorf("failed to run %s: %v", cmd, err)
+	}
+	return string(out), 0, nil
+}
+
 // Prompt asks a user for an input (y/n)
 // and returns boolean representing:
 // true if lowercase answer is (y) and false otherwise
@@ -45,6 +90,22 @@ func FPrompt(ask string) bool {
 	return userInput == "y"
 }
 
+// FPromptChoice asks a user to pick one of choices
+// and repeats the question until the answer is valid.
+func FPromptChoice(ask string, choices []string) string {
+	for {
+		var userInput string
+		fmt.Printf("%s (%s): \n", ask, strings.Join(choices, "/"))
+		fmt.Scanln(&userInput)
+		userInput = strings.ToLower(userInput)
+		for _, c := range choices {
+			if userInput == c {
+				return c
+			}
+		}
+	}
+}
+
 func FReflectName(i any) string {
 	return reflect.TypeOf(i).Elem().Name()
 }
@@ -64,3 +125,143 @@ func FCreateDir(path string) error {
 	}
 	return nil
 }
+
+// FNormalizeFingerprint removes spaces and uppercases OpenPGP fingerprint.
+func FNormalizeFingerprint(fingerprint string) string {
+	return strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+}
+
+// FLevenshtein returns edit distance between two strings.
+func FLevenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// FSuggest returns up to limit candidates closest to input,
+// ignoring ones which are too far to be a typo.
+func FSuggest(input string, candidates []string, limit int) []string {
+	maxDistance := max(2, len(input)/3)
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, c := range candidates {
+		if d := FLevenshtein(input, c); d <= maxDistance {
+			matches = append(matches, match{name: c, distance: d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var result []string
+	for i := 0; i < len(matches) && i < limit; i++ {
+		result = append(result, matches[i].name)
+	}
+	return result
+}
+
+// FShellQuote quotes string with single quotes for POSIX shell.
+func FShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// FShortCommit shortens commit hash for display.
+func FShortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// FIsCommitHash reports whether s is full SHA-1 or SHA-256 commit hash.
+// Abbreviated hashes are not accepted, they may as well be branch or tag names.
+func FIsCommitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// FLuaString quotes string as Lua string literal.
+func FLuaString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// FLuaValue renders bool, number, string or list of strings as Lua literal.
+func FLuaValue(v any) (string, error) {
+	switch v := v.(type) {
+	case bool:
+		return fmt.Sprint(v), nil
+	case int, float64:
+		return fmt.Sprint(v), nil
+	case string:
+		return FLuaString(v), nil
+	case []string:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		var items []string
+		for _, s := range v {
+			items = append(items, FLuaString(s))
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	default:
+		return "", fmt.Errorf("unsupported Lua value type %T", v)
+	}
+}
+
+// FParseChecksums parses sha256sum output, such as SHASUMS256.txt,
+// into digests keyed by file name.
+func FParseChecksums(text string) map[string]string {
+	sums := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// binary mode marks file name with asterisk
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
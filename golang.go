@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GoRelease is an entry of go.dev download feed,
+// which lists releases from the newest one.
+type GoRelease struct {
+	Version string   `json:"version"`
+	Stable  bool     `json:"stable"`
+	Files   []GoFile `json:"files"`
+}
+
+type GoFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Version  string `json:"version"`
+	SHA256   string `json:"sha256"`
+	Kind     string `json:"kind"`
+}
+
+// GoTool is a module installed with go install at pinned version.
+type GoTool struct {
+	module  string
+	version string
+}
+
+// FParseGoReleases parses go.dev download feed requested with mode=json.
+func FParseGoReleases(data []byte) ([]GoRelease, error) {
+	var releases []GoRelease
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse go release feed: %v", err)
+	}
+	return releases, nil
+}
+
+// FResolveGoRelease picks archive for os and arch. Version is either exact,
+// e.g. 1.24.1 or 1.25rc1, a minor series such as 1.24, which resolves
+// to its newest stable patch, or empty for the newest stable release.
+func FResolveGoRelease(releases []GoRelease, version, goos, goarch string) (GoFile, error) {
+	version = strings.TrimPrefix(version, "go")
+	for _, r := range releases {
+		v := strings.TrimPrefix(r.Version, "go")
+		isMatch := v == version ||
+			(r.Stable && (len(version) == 0 || strings.HasPrefix(v, version+".")))
+		if !isMatch {
+			continue
+		}
+		for _, f := range r.Files {
+			if f.Kind == "archive" && f.OS == goos && f.Arch == goarch {
+				return f, nil
+			}
+		}
+		return GoFile{}, fmt.Errorf("go %s has no archive for %s/%s", v, goos, goarch)
+	}
+	if len(version) == 0 {
+		version = "stable"
+	}
+	return GoFile{}, fmt.Errorf("go %s is not found in release feed", version)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type GoToolchainConfig struct {
+	// root is toolchains directory, go versions are kept in root/go.
+	root string
+	// version is exact version, minor series or empty for the newest stable.
+	version string
+	// baseURL serves release feed and archives, e.g. https://go.dev/dl or a mirror.
+	baseURL string
+	goos    string
+	goarch  string
+	goPath  string
+	goBin   string
+	tools   []GoTool
+	tmpDir  string
+	// shrc receives PATH, GOPATH and GOBIN in addition to its own env.
+	shrc ShrcConfig
+}
+
+// Spec returns toolchain spec of resolved release file.
+func (c GoToolchainConfig) Spec(file GoFile) ToolchainSpec {
+	spec := ToolchainSpec{
+		name:    "go",
+		version: strings.TrimPrefix(file.Version, "go"),
+		url:     c.baseURL + "/" + file.Filename,
+		sha256:  file.SHA256,
+		strip:   1,
+		binDir:  "bin",
+		env: []EnvChange{
+			{kind: EnvVar, name: "GOPATH", value: c.goPath},
+			{kind: EnvVar, name: "GOBIN", value: c.goBin},
+			{kind: EnvPath, value: c.goBin},
+		},
+		probe: []string{"go", "version"},
+	}
+	for _, tool := range c.tools {
+		// tools must be built by installed version, not by one it would download
+		command := fmt.Sprintf("GOPATH=%s GOBIN=%s GOTOOLCHAIN=local go install %s",
+			FShellQuote(c.goPath), FShellQuote(c.goBin), FShellQuote(tool.module+"@"+tool.version))
+		spec.postInstall = append(spec.postInstall, command)
+	}
+	return spec
+}
+
+// GoToolchainTask installs Go release verified against checksum
+// from the download feed and Go tools at pinned versions.
+type GoToolchainTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *GoToolchainTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(GoToolchainConfig)
+
+	if len(cfg.root) == 0 {
+		return FPrefixError(t.Name, "toolchain root is empty")
+	}
+	if err := t.vh.ValidateURL(cfg.baseURL); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(cfg.goos) == 0 || len(cfg.goarch) == 0 {
+		return FPrefixError(t.Name, "goos and goarch are required")
+	}
+	if len(cfg.goPath) == 0 || len(cfg.goBin) == 0 {
+		return FPrefixError(t.Name, "GOPATH and GOBIN are required")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, tool := range cfg.tools {
+		if len(tool.version) == 0 || tool.version == "latest" {
+			return FPrefixError(t.Name, fmt.Sprintf("go tool %s must be pinned to a version", tool.module))
+		}
+	}
+	if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *GoToolchainTask) Run() error {
+	cfg, _ := t.Config.(GoToolchainConfig)
+
+	file, err := t.resolve(cfg)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	task := ToolchainTask{
+		BaseTask: BaseTask{
+			Name: t.Name,
+			Config: ToolchainConfig{
+				root:   cfg.root,
+				spec:   cfg.Spec(file),
+				tmpDir: cfg.tmpDir,
+				shrc:   cfg.shrc,
+			},
+			Journal: t.Journal,
+		},
+	}
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	return task.Run()
+}
+
+// resolve fetches release feed and picks archive of requested version.
+func (t *GoToolchainTask) resolve(cfg GoToolchainConfig) (GoFile, error) {
+	feedPath := filepath.Join(cfg.tmpDir, "go-releases.json")
+	if err := t.th.Download(cfg.baseURL+"/?mode=json&include=all", feedPath, false); err != nil {
+		return GoFile{}, err
+	}
+	data, err := os.ReadFile(feedPath)
+	if err != nil {
+		return GoFile{}, fmt.Errorf("failed to read go release feed: %v", err)
+	}
+	releases, err := FParseGoReleases(data)
+	if err != nil {
+		return GoFile{}, err
+	}
+	return FResolveGoRelease(releases, cfg.version, cfg.goos, cfg.goarch)
+}
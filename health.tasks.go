@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type HealthLevel int
+
+const (
+	HealthOK HealthLevel = iota + 1
+	HealthWarning
+	HealthError
+)
+
+func (l HealthLevel) String() string {
+	switch l {
+	case HealthOK:
+		return "OK"
+	case HealthWarning:
+		return "WARNING"
+	case HealthError:
+		return "ERROR"
+	}
+	return "NONE"
+}
+
+type HealthEntry struct {
+	// section is checkhealth section such as provider.python or vim.lsp,
+	// startup errors are reported in "startup" section.
+	section string
+	level   HealthLevel
+	message string
+}
+
+// FParseCheckhealth parses :checkhealth buffer into OK/WARNING/ERROR entries.
+// Entries may be prefixed with status icons, as newer Neovim versions do.
+func FParseCheckhealth(out string) []HealthEntry {
+	var entries []HealthEntry
+	section := ""
+	isHeader := false
+	for _, line := range strings.Split(out, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=====") {
+			isHeader = true
+			continue
+		}
+		if isHeader && len(trimmed) > 0 {
+			section, _, _ = strings.Cut(trimmed, ":")
+			section = strings.TrimSpace(section)
+			isHeader = false
+			continue
+		}
+
+		item, ok := strings.CutPrefix(trimmed, "- ")
+		if !ok {
+			continue
+		}
+		item = strings.TrimLeft(item, "✅⚠️❌ ")
+		for _, level := range []HealthLevel{HealthOK, HealthWarning, HealthError} {
+			if msg, ok := strings.CutPrefix(item, level.String()); ok {
+				entries = append(entries, HealthEntry{
+					section: section,
+					level:   level,
+					message: strings.TrimSpace(msg),
+				})
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// FParseStartupErrors detects errors Neovim prints while loading config.
+func FParseStartupErrors(out string) []HealthEntry {
+	var entries []HealthEntry
+	for _, line := range strings.Split(out, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Error detected while processing") ||
+			strings.HasPrefix(trimmed, "E5113:") ||
+			strings.HasPrefix(trimmed, "E5108:") {
+			entries = append(entries, HealthEntry{section: "startup", level: HealthError, message: trimmed})
+		}
+	}
+	return entries
+}
+
+type NeovimHealthConfig struct {
+	nvimPath string
+	tmpDir   string
+	// failOn is the lowest level which fails the task,
+	// zero value only reports entries.
+	failOn HealthLevel
+	// ignore lists sections which are neither reported nor counted.
+	ignore []string
+}
+
+// NeovimHealthTask starts Neovim headless with user config,
+// runs :checkhealth and reports warnings and errors.
+type NeovimHealthTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *NeovimHealthTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(NeovimHealthConfig)
+
+	if err := t.vh.ValidatePath(cfg.nvimPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *NeovimHealthTask) Run() error {
+	cfg, _ := t.Config.(NeovimHealthConfig)
+	outPath := filepath.Join(cfg.tmpDir, "checkhealth.txt")
+
+	args := []string{"--headless", "+checkhealth", "+w! " + outPath, "+qa!"}
+	startupOut, _, err := FRunCommandCombined(cfg.nvimPath, args, false)
+	if err != nil {
+		return FPrefixError(t.Name, fmt.Sprintf("neovim failed to start: %v\n%s", err, startupOut))
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		return FPrefixError(t.Name, fmt.Sprintf("checkhealth output is missing: %v", err))
+	}
+
+	entries := append(FParseStartupErrors(startupOut), FParseCheckhealth(string(data))...)
+	counts := map[HealthLevel]int{}
+	var failed []string
+	for _, e := range entries {
+		if slices.Contains(cfg.ignore, e.section) {
+			continue
+		}
+		counts[e.level]++
+		switch e.level {
+		case HealthWarning:
+			slog.Warn(e.message, "task_name", t.Name, "section", e.section)
+		case HealthError:
+			slog.Error(e.message, "task_name", t.Name, "section", e.section)
+		}
+		if cfg.failOn > 0 && e.level >= cfg.failOn {
+			failed = append(failed, fmt.Sprintf("%s: %s %s", e.section, e.level, e.message))
+		}
+	}
+	slog.Info("checkhealth finished", "task_name", t.Name, "ok", counts[HealthOK], "warning", counts[HealthWarning], "error", counts[HealthError])
+
+	if len(failed) > 0 {
+		return FPrefixError(t.Name, "checkhealth reported:\n"+strings.Join(failed, "\n"))
+	}
+	return nil
+}
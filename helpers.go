@@ -1,27 +1,46 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 type TaskHelper struct{}
 
-// IsPackageInstalled uses dpkg-query with --status flag to verify if package exists.
-// Will print package information if present or unavailability otherwise.
+// IsPackageInstalled uses InstalledPackageVersion to verify if package exists.
 func (t TaskHelper) IsPackageInstalled(pkgName string, isSudo bool) (bool, error) {
+	_, isInstalled, err := t.InstalledPackageVersion(pkgName, isSudo)
+	return isInstalled, err
+}
+
+// InstalledPackageVersion uses dpkg-query with --show flag and custom format
+// to read package status and version without printing the whole status.
+// Returns empty version and false if package is unknown or not installed.
+func (t TaskHelper) InstalledPackageVersion(pkgName string, isSudo bool) (string, bool, error) {
 	cmd := "dpkg-query"
-	args := []string{"--status", pkgName}
-	errCode, err := FRunCommand(cmd, args, isSudo)
+	args := []string{"--show", "--showformat=${db:Status-Status} ${Version}", pkgName}
+	out, errCode, err := FRunCommandOutput(cmd, args, isSudo)
 	if err != nil && errCode == 1 {
-		return false, nil
+		return "", false, nil
 	}
 	if err != nil {
-		return false, fmt.Errorf("failed to check package presence: %v", err)
+		return "", false, fmt.Errorf("failed to check package presence: %v", err)
+	}
+
+	status, version, _ := strings.Cut(strings.TrimSpace(out), " ")
+	if status != "installed" {
+		return "", false, nil
 	}
-	return true, nil
+	return version, true, nil
 }
 
 // IsPathEmpty checks if path exists and is empty.
@@ -36,13 +55,376 @@ func (t TaskHelper) IsPathEmpty(path string) (bool, error) {
 	return false, nil
 }
 
-// CloneGitRepo executed git clone command with git url and destination as arguments.
-func (t TaskHelper) GitClone(repoURL, targetDir string, isSudo bool) error {
+// SwitchCurrent points current symlink of a tool directory to version directory,
+// versions of a tool are installed side by side, e.g. toolchains/go/1.24.1.
+func (t TaskHelper) SwitchCurrent(toolDir, versionDir string, isSudo bool) error {
+	current := filepath.Join(toolDir, "current")
+	if err := t.DeletePath(current, isSudo); err != nil {
+		return err
+	}
+	return t.Symlink(versionDir, current, isSudo)
+}
+
+// GitCloneOptions controls how GitClone checks out repository.
+type GitCloneOptions struct {
+	// ref is branch, tag or commit, empty ref means default branch.
+	ref string
+	// depth makes shallow clone with given history depth, 0 clones full history.
+	depth int
+	// filter is partial clone filter, e.g. blob:none.
+	filter string
+	// submodules initializes submodules recursively.
+	submodules bool
+	// ensure reuses existing clone of the same remote,
+	// it is fetched and checked out at ref instead of failing.
+	ensure bool
+}
+
+// GitClone clones repository into targetDir and checks out ref from opts.
+// Branches and tags are cloned with --branch, commits are fetched after clone.
+func (t TaskHelper) GitClone(repoURL, targetDir string, opts GitCloneOptions, isSudo bool) error {
+	if _, err := os.Stat(filepath.Join(targetDir, ".git")); err == nil && opts.ensure {
+		remote, err := t.GitRemoteURL(targetDir)
+		if err != nil {
+			return err
+		}
+		if remote != repoURL {
+			return fmt.Errorf("%s is a clone of %s, not %s", targetDir, remote, repoURL)
+		}
+		return t.gitSync(targetDir, opts, isSudo)
+	}
+
 	cmd := "git"
-	args := []string{"clone", repoURL, targetDir}
+	args := []string{"clone", "--quiet"}
+	if opts.depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.depth))
+	}
+	if len(opts.filter) > 0 {
+		args = append(args, "--filter="+opts.filter)
+	}
+	isCommit, err := t.gitIsCommit(repoURL, opts.ref)
+	if err != nil {
+		return err
+	}
+	if isCommit {
+		args = append(args, "--no-checkout")
+	} else if len(opts.ref) > 0 {
+		args = append(args, "--branch", opts.ref)
+	}
+	args = append(args, repoURL, targetDir)
 	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
 		return fmt.Errorf("failed to clone git repository: %v", err)
 	}
+	if isCommit {
+		commit, err := t.gitFetchRef(targetDir, opts, isSudo)
+		if err != nil {
+			return err
+		}
+		if err := t.GitCheckout(targetDir, commit, isSudo); err != nil {
+			return err
+		}
+	}
+	return t.gitSubmodules(targetDir, opts, isSudo)
+}
+
+// gitSync fetches ref from origin and checks it out as detached HEAD.
+// Checkout fails instead of discarding local modifications of tracked files.
+func (t TaskHelper) gitSync(dir string, opts GitCloneOptions, isSudo bool) error {
+	if _, ok := t.GitRevParse(dir, opts.ref); !ok || !FIsCommitHash(opts.ref) {
+		remote, err := t.GitRemoteURL(dir)
+		if err != nil {
+			return err
+		}
+		if _, err := t.gitIsCommit(remote, opts.ref); err != nil {
+			return err
+		}
+	}
+	commit, err := t.gitFetchRef(dir, opts, isSudo)
+	if err != nil {
+		return err
+	}
+	if head, _ := t.GitRevParse(dir, "HEAD"); head != commit {
+		if err := t.gitCheckClean(dir); err != nil {
+			return err
+		}
+		if err := t.GitCheckout(dir, commit, isSudo); err != nil {
+			return err
+		}
+	}
+	return t.gitSubmodules(dir, opts, isSudo)
+}
+
+// gitFetchRef fetches ref from origin and returns its commit.
+// Commits which are already present are not fetched.
+func (t TaskHelper) gitFetchRef(dir string, opts GitCloneOptions, isSudo bool) (string, error) {
+	if commit, ok := t.GitRevParse(dir, opts.ref); ok && FIsCommitHash(opts.ref) {
+		return commit, nil
+	}
+	cmd := "git"
+	args := []string{"-C", dir, "fetch", "--quiet", "--force"}
+	if opts.depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.depth))
+	}
+	ref := opts.ref
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+	args = append(args, "origin", ref)
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %v", ref, err)
+	}
+	commit, ok := t.GitRevParse(dir, "FETCH_HEAD")
+	if !ok {
+		return "", fmt.Errorf("failed to resolve fetched %s in %s", ref, dir)
+	}
+	return commit, nil
+}
+
+// gitIsCommit resolves ref on remote first, so a branch or tag named
+// like a hash is not taken for a commit. Refs missing on remote are
+// accepted only as full commit hashes.
+func (t TaskHelper) gitIsCommit(remote, ref string) (bool, error) {
+	if len(ref) == 0 {
+		return false, nil
+	}
+	cmd := "git"
+	args := []string{"ls-remote", "--quiet", remote, ref}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to list refs of %s: %v", remote, err)
+	}
+	if len(strings.TrimSpace(out)) > 0 {
+		return false, nil
+	}
+	if FIsCommitHash(ref) {
+		return true, nil
+	}
+	return false, fmt.Errorf("%s is neither branch or tag of %s nor full commit hash", ref, remote)
+}
+
+// gitCheckClean fails if tracked files of checkout are modified,
+// untracked files such as plugin build outputs are kept by checkout.
+func (t TaskHelper) gitCheckClean(dir string) error {
+	cmd := "git"
+	args := []string{"-C", dir, "status", "--porcelain", "--untracked-files=no"}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return fmt.Errorf("failed to read git status: %v", err)
+	}
+	if changes := strings.TrimSpace(out); len(changes) > 0 {
+		return fmt.Errorf("%s has local modifications, commit or discard them before update:\n%s", dir, changes)
+	}
+	return nil
+}
+
+func (t TaskHelper) gitSubmodules(dir string, opts GitCloneOptions, isSudo bool) error {
+	if !opts.submodules {
+		return nil
+	}
+	cmd := "git"
+	args := []string{"-C", dir, "submodule", "--quiet", "update", "--init", "--recursive"}
+	if opts.depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.depth))
+	}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to update git submodules: %v", err)
+	}
+	return nil
+}
+
+// GitFetch fetches branches and tags from origin of repository in dir.
+func (t TaskHelper) GitFetch(dir string, isSudo bool) error {
+	cmd := "git"
+	args := []string{"-C", dir, "fetch", "--tags", "--force", "origin"}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to fetch git repository: %v", err)
+	}
+	return nil
+}
+
+// GitCheckout checks out ref as detached HEAD,
+// local changes of tracked files are discarded.
+func (t TaskHelper) GitCheckout(dir, ref string, isSudo bool) error {
+	cmd := "git"
+	args := []string{"-C", dir, "checkout", "--quiet", "--force", "--detach", ref}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to checkout %s: %v", ref, err)
+	}
+	return nil
+}
+
+// GitRevParse resolves revision to commit hash,
+// returns false if revision does not exist.
+func (t TaskHelper) GitRevParse(dir, rev string) (string, bool) {
+	cmd := "git"
+	args := []string{"-C", dir, "rev-parse", "--quiet", "--verify", rev + "^{commit}"}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(out), true
+}
+
+// GitLockedClone clones repository with GitClone at commit locked for a source,
+// or at opts.ref which is then locked if source is not locked yet.
+// It returns checked out commit.
+func (t TaskHelper) GitLockedClone(repoURL, dir, name string, opts GitCloneOptions, lock *Lockfile, isSudo bool) (string, error) {
+	ref := opts.ref
+	commit, locked := lock.Commit(name, repoURL, ref)
+	if locked {
+		opts.ref = commit
+	}
+	if err := t.GitClone(repoURL, dir, opts, isSudo); err != nil {
+		return "", err
+	}
+	if locked {
+		return commit, nil
+	}
+	commit, ok := t.GitRevParse(dir, "HEAD")
+	if !ok {
+		return "", fmt.Errorf("failed to resolve checked out commit in %s", dir)
+	}
+	lock.Set(name, LockEntry{URL: repoURL, Ref: ref, Commit: commit})
+	return commit, nil
+}
+
+// GitMirror keeps bare, blobless clone of repository in dir
+// and fetches every branch and tag into it.
+func (t TaskHelper) GitMirror(repoURL, dir string) error {
+	cmd := "git"
+	args := []string{"clone", "--quiet", "--bare", "--filter=blob:none", repoURL, dir}
+	if _, err := os.Stat(dir); err == nil {
+		args = []string{"--git-dir", dir, "fetch", "--quiet", "--tags", "--force", "--prune", repoURL, "+refs/heads/*:refs/heads/*"}
+	}
+	if _, err := FRunCommand(cmd, args, false); err != nil {
+		return fmt.Errorf("failed to mirror git repository: %v", err)
+	}
+	return nil
+}
+
+// GitLog returns one line "<short hash> <subject>" per commit
+// reachable from to, but not from from.
+func (t TaskHelper) GitLog(dir, from, to string) ([]string, error) {
+	cmd := "git"
+	args := []string{"-C", dir, "log", "--format=%h %s", from + ".." + to}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log: %v", err)
+	}
+	out = strings.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// GitRemoteURL returns url of origin remote.
+func (t TaskHelper) GitRemoteURL(dir string) (string, error) {
+	cmd := "git"
+	args := []string{"-C", dir, "remote", "get-url", "origin"}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to read git remote: %v", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// GitReset moves current branch to commit with --hard flag.
+func (t TaskHelper) GitReset(dir, commit string, isSudo bool) error {
+	cmd := "git"
+	args := []string{"-C", dir, "reset", "--quiet", "--hard", commit}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to reset to %s: %v", commit, err)
+	}
+	return nil
+}
+
+// GitStatus returns porcelain status lines of changed files under paths.
+func (t TaskHelper) GitStatus(dir string, paths ...string) ([]string, error) {
+	cmd := "git"
+	args := append([]string{"-C", dir, "status", "--porcelain", "--"}, paths...)
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git status: %v", err)
+	}
+	out = strings.TrimRight(out, "\n")
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// GitCurrentBranch returns checked out branch name,
+// fails with detached HEAD.
+func (t TaskHelper) GitCurrentBranch(dir string) (string, error) {
+	cmd := "git"
+	args := []string{"-C", dir, "symbolic-ref", "--quiet", "--short", "HEAD"}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to read current branch, is HEAD detached? %v", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// GitAheadBehind counts commits of HEAD missing in upstream (ahead)
+// and commits of upstream missing in HEAD (behind).
+func (t TaskHelper) GitAheadBehind(dir, upstream string) (int, int, error) {
+	cmd := "git"
+	args := []string{"-C", dir, "rev-list", "--left-right", "--count", "HEAD..." + upstream}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare with %s: %v", upstream, err)
+	}
+	var ahead, behind int
+	if _, err := fmt.Sscan(out, &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("failed to compare with %s: %v", upstream, err)
+	}
+	return ahead, behind, nil
+}
+
+// GitMergeFastForward merges upstream into current branch
+// only if it can be fast-forwarded.
+func (t TaskHelper) GitMergeFastForward(dir, upstream string, isSudo bool) error {
+	cmd := "git"
+	args := []string{"-C", dir, "merge", "--quiet", "--ff-only", upstream}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to fast-forward to %s: %v", upstream, err)
+	}
+	return nil
+}
+
+// GitCommit stages changes under paths and commits them.
+func (t TaskHelper) GitCommit(dir, message string, isSudo bool, paths ...string) error {
+	cmd := "git"
+	args := append([]string{"-C", dir, "add", "--all", "--"}, paths...)
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to stage changes: %v", err)
+	}
+	args = []string{"-C", dir, "commit", "--quiet", "--message", message}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to commit changes: %v", err)
+	}
+	return nil
+}
+
+// GitPush pushes current HEAD into branch of origin.
+func (t TaskHelper) GitPush(dir, branch string, isSudo bool) error {
+	cmd := "git"
+	args := []string{"-C", dir, "push", "--quiet", "origin", "HEAD:refs/heads/" + branch}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to push: %v", err)
+	}
+	return nil
+}
+
+// GitDiff prints diff between revisions, or of working tree
+// if no revisions are given.
+func (t TaskHelper) GitDiff(dir string, revs ...string) error {
+	cmd := "git"
+	args := append([]string{"-C", dir, "--no-pager", "diff"}, revs...)
+	if _, err := FRunCommand(cmd, args, false); err != nil {
+		return fmt.Errorf("failed to show diff: %v", err)
+	}
 	return nil
 }
 
@@ -56,6 +438,45 @@ func (t TaskHelper) ExtractTar(file, path string, isSudo bool) error {
 	return nil
 }
 
+// ExtractArchive uses tar xf, which detects gzip, xz and bzip2 compression.
+// Strip removes leading components from member names, e.g. top level directory.
+func (t TaskHelper) ExtractArchive(file, path string, strip int, isSudo bool) error {
+	cmd := "tar"
+	args := []string{"xf", file, "-C", path}
+	if strip > 0 {
+		args = append(args, "--strip-components", strconv.Itoa(strip))
+	}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to extract archive: %v", err)
+	}
+	return nil
+}
+
+// ExtractZip uses unzip with -o flag, which overwrites existing files.
+func (t TaskHelper) ExtractZip(file, path string, isSudo bool) error {
+	cmd := "unzip"
+	args := []string{"-q", "-o", file, "-d", path}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to extract zip file: %v", err)
+	}
+	return nil
+}
+
+// FileSHA256 returns hex encoded SHA-256 digest of a file.
+func (t TaskHelper) FileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to open a file %s: %v", file, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read a file %s: %v", file, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // Move executes mv command.
 func (t TaskHelper) Move(src, dst string, isSudo bool) error {
 	cmd := "mv"
@@ -66,6 +487,27 @@ func (t TaskHelper) Move(src, dst string, isSudo bool) error {
 	return nil
 }
 
+// Copy executes cp with --archive flag, which preserves
+// permissions and copies directories recursively.
+func (t TaskHelper) Copy(src, dst string, isSudo bool) error {
+	cmd := "cp"
+	args := []string{"--archive", src, dst}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to copy: %v", err)
+	}
+	return nil
+}
+
+// Symlink executes ln with --symbolic flag.
+func (t TaskHelper) Symlink(target, link string, isSudo bool) error {
+	cmd := "ln"
+	args := []string{"--symbolic", target, link}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to create symlink: %v", err)
+	}
+	return nil
+}
+
 // Download makes use of curl with -L and -o flags.
 func (t TaskHelper) Download(url, path string, isSudo bool) error {
 	cmd := "curl"
@@ -151,6 +593,315 @@ func (t TaskHelper) AppendContent(file, content string) error {
 	return nil
 }
 
+// CreateDir executes mkdir with --parents flag.
+func (t TaskHelper) CreateDir(path string, isSudo bool) error {
+	cmd := "mkdir"
+	args := []string{"--parents", path}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	return nil
+}
+
+// WriteFile writes content into a temporary file
+// and copies it to destination with install -m.
+// Makes it possible to write into root owned directories.
+func (t TaskHelper) WriteFile(file, content, perm string, isSudo bool) error {
+	tmp, err := os.CreateTemp("", "autonvim-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+
+	cmd := "install"
+	args := []string{"-m", perm, tmp.Name(), file}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", file, err)
+	}
+	return nil
+}
+
+// KeyFingerprints uses gpg --show-keys to list primary key fingerprints
+// of OpenPGP key file without importing it.
+func (t TaskHelper) KeyFingerprints(file string) ([]string, error) {
+	cmd := "gpg"
+	args := []string{"--show-keys", "--with-colons", "--with-fingerprint", file}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key fingerprint: %v", err)
+	}
+
+	var fingerprints []string
+	var record string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, ":")
+		switch fields[0] {
+		case "pub", "sub":
+			record = fields[0]
+		case "fpr":
+			if record == "pub" && len(fields) > 9 {
+				fingerprints = append(fingerprints, fields[9])
+			}
+		}
+	}
+	return fingerprints, nil
+}
+
+// AptUpdate refreshes package index with apt update.
+func (t TaskHelper) AptUpdate(isSudo bool) error {
+	cmd := "apt"
+	args := []string{"update"}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to update package index: %v", err)
+	}
+	return nil
+}
+
+// PackageCandidate uses apt-cache policy to find the version
+// that would be installed from package index.
+// Returns false if package is unknown or has no installation candidate.
+func (t TaskHelper) PackageCandidate(pkgName string) (string, bool, error) {
+	cmd := "apt-cache"
+	args := []string{"policy", pkgName}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to query package index: %v", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "Candidate:")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "(none)" {
+			return "", false, nil
+		}
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// PackageNames lists every package name known to package index
+// using apt-cache pkgnames.
+func (t TaskHelper) PackageNames() ([]string, error) {
+	cmd := "apt-cache"
+	args := []string{"pkgnames"}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list package names: %v", err)
+	}
+	return strings.Fields(out), nil
+}
+
+// DebField uses dpkg-deb --field to read control field of .deb file.
+func (t TaskHelper) DebField(file, field string) (string, error) {
+	cmd := "dpkg-deb"
+	args := []string{"--field", file, field}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s of %s: %v", field, file, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// WriteBlock writes managed block into a file, replacing
+// existing block with the same id in place. File is created if missing.
+func (t TaskHelper) WriteBlock(file string, block ManagedBlock) error {
+	return t.editFile(file, func(text string) (string, error) {
+		return FReplaceBlock(text, block)
+	})
+}
+
+// RemoveBlock deletes managed block with given id from a file.
+// Missing file or block is not an error.
+func (t TaskHelper) RemoveBlock(file, id string) error {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil
+	}
+	return t.editFile(file, func(text string) (string, error) {
+		return FRemoveBlock(text, id)
+	})
+}
+
+// editFile applies edit to file content, unchanged files are not touched.
+// Callers take backup snapshot of the file first.
+func (t TaskHelper) editFile(file string, edit func(string) (string, error)) error {
+	// rc files are often symlinks into dotfiles repository,
+	// temp file is renamed over the target to keep the link
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to resolve a file %s: %v", file, err)
+	}
+	perm := os.FileMode(0644)
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read a file %s: %v", file, err)
+	}
+	isExisting := err == nil
+	if isExisting {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to check path stat: %v", err)
+		}
+		perm = info.Mode().Perm()
+	}
+
+	edited, err := edit(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to edit a file %s: %v", file, err)
+	}
+	if isExisting && edited == string(data) {
+		return nil
+	}
+
+	tmp := file + ".autonvim.tmp"
+	if err := os.WriteFile(tmp, []byte(edited), perm); err != nil {
+		return fmt.Errorf("failed to write a file %s: %v", file, err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write a file %s: %v", file, err)
+	}
+	return nil
+}
+
+// DetectShells returns login shell taken from $SHELL
+// together with every shell whose startup file exists in homePath.
+func (t TaskHelper) DetectShells(homePath string) []Shell {
+	var shells []Shell
+	login := Shell(filepath.Base(os.Getenv("SHELL")))
+	for _, s := range Shells {
+		if _, err := os.Stat(s.RcPath(homePath)); err == nil || s == login {
+			shells = append(shells, s)
+		}
+	}
+	return shells
+}
+
+// WriteShellEnv renders environment for each shell and writes it
+// as managed block into shell startup file, taking snapshot of it first.
+// Returns list of files that contain the block.
+func (t TaskHelper) WriteShellEnv(cfg ShrcConfig, backup *Backup) ([]string, error) {
+	shells := cfg.shells
+	if len(shells) == 0 {
+		shells = t.DetectShells(cfg.homePath)
+	}
+
+	var files []string
+	for _, s := range shells {
+		content := FRenderEnv(s, cfg.env)
+		if len(content) == 0 {
+			continue
+		}
+		file := s.RcPath(cfg.homePath)
+		if err := FCreateDir(filepath.Dir(file)); err != nil {
+			return files, err
+		}
+		if err := backup.Snapshot(file, false); err != nil {
+			return files, err
+		}
+		if err := t.WriteBlock(file, ManagedBlock{id: cfg.id, content: content}); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// RemoveContent removes every occurrence of content from a file,
+// reverting what AppendContent did. Missing file is not an error.
+func (t TaskHelper) RemoveContent(file, content string) error {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read a file %s: %v", file, err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("failed to check path stat: %v", err)
+	}
+	stripped := strings.ReplaceAll(string(data), content, "")
+	if err := os.WriteFile(file, []byte(stripped), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to remove content: %v", err)
+	}
+	return nil
+}
+
+// FontFamilies returns font families known to fontconfig.
+func (t TaskHelper) FontFamilies() ([]string, error) {
+	cmd := "fc-list"
+	args := []string{":", "family"}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fonts: %v", err)
+	}
+	var families []string
+	for _, line := range strings.Split(out, "\n") {
+		// fonts with localized names list every name separated by comma
+		for _, family := range strings.Split(line, ",") {
+			if family = strings.TrimSpace(family); len(family) > 0 {
+				families = append(families, family)
+			}
+		}
+	}
+	return families, nil
+}
+
+// RefreshFontCache rebuilds fontconfig cache of dir.
+func (t TaskHelper) RefreshFontCache(dir string, isSudo bool) error {
+	cmd := "fc-cache"
+	args := []string{"--force", dir}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to refresh font cache: %v", err)
+	}
+	return nil
+}
+
+// LoginShell reads user login shell from getent passwd.
+func (t TaskHelper) LoginShell(username string) (string, error) {
+	cmd := "getent"
+	args := []string{"passwd", username}
+	out, _, err := FRunCommandOutput(cmd, args, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to read login shell: %v", err)
+	}
+	fields := strings.Split(strings.TrimSpace(out), ":")
+	if len(fields) < 7 {
+		return "", fmt.Errorf("failed to read login shell, unexpected passwd entry")
+	}
+	return fields[6], nil
+}
+
+// ChangeShell executes chsh with -s flag.
+func (t TaskHelper) ChangeShell(username, shell string, isSudo bool) error {
+	cmd := "/usr/bin/chsh"
+	args := []string{username, "-s", shell}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to change shell: %v", err)
+	}
+	return nil
+}
+
+// PurgePackage executes apt purge and removes dependencies
+// which are no longer needed.
+func (t TaskHelper) PurgePackage(pkgName string, isSudo bool) error {
+	cmd := "apt"
+	args := []string{"purge", "--yes", "--autoremove", pkgName}
+	if _, err := FRunCommand(cmd, args, isSudo); err != nil {
+		return fmt.Errorf("failed to purge package: %v", err)
+	}
+	return nil
+}
+
 type ValidationHelper struct{}
 
 func (v ValidationHelper) ValidateBaseTask(t BaseTask, config any) error {
@@ -184,6 +935,62 @@ func (v ValidationHelper) ValidatePath(path string, checkIfDir bool) error {
 	return nil
 }
 
+// ValidateFingerprint checks that fingerprint is 40 hex characters long,
+// spaces are ignored.
+func (v ValidationHelper) ValidateFingerprint(fingerprint string) error {
+	fingerprint = FNormalizeFingerprint(fingerprint)
+	if len(fingerprint) != 40 {
+		return fmt.Errorf("validation failed, fingerprint must be 40 hex characters")
+	}
+	for _, r := range fingerprint {
+		if !strings.ContainsRune("0123456789ABCDEF", r) {
+			return fmt.Errorf("validation failed, fingerprint contains non hex character")
+		}
+	}
+	return nil
+}
+
+// ValidateBlock checks that managed block has an id usable in markers.
+func (v ValidationHelper) ValidateBlock(block ManagedBlock) error {
+	if len(block.id) == 0 || strings.ContainsAny(block.id, " \t\n") {
+		return fmt.Errorf("validation failed, block id must be non-empty and contain no whitespace")
+	}
+	if strings.Contains(block.content, "# BEGIN autonvim:") || strings.Contains(block.content, "# END autonvim:") {
+		return fmt.Errorf("validation failed, block content contains markers")
+	}
+	return nil
+}
+
+// ValidateShrc checks shell environment declaration.
+func (v ValidationHelper) ValidateShrc(cfg ShrcConfig) error {
+	if err := v.ValidatePath(cfg.homePath, true); err != nil {
+		return err
+	}
+	if err := v.ValidateBlock(ManagedBlock{id: cfg.id}); err != nil {
+		return err
+	}
+	for _, s := range cfg.shells {
+		if !slices.Contains(Shells, s) {
+			return fmt.Errorf("validation failed, unsupported shell %s", s)
+		}
+	}
+	for _, e := range cfg.env {
+		switch e.kind {
+		case EnvPath, EnvSource:
+		case EnvVar:
+			if len(e.name) == 0 || strings.ContainsAny(e.name, " =$\"'") {
+				return fmt.Errorf("validation failed, invalid variable name '%s'", e.name)
+			}
+		default:
+			return fmt.Errorf("validation failed, unknown environment change %s", e.kind)
+		}
+		if len(e.value) == 0 {
+			return fmt.Errorf("validation failed, empty %s value", e.kind)
+		}
+	}
+	return nil
+}
+
 func (v ValidationHelper) ValidateURL(input string) error {
 	_, err := url.ParseRequestURI(input)
 	return err
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteBlockKeepsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "zshrc")
+	link := filepath.Join(dir, ".zshrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, target, "# mine\n")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	var th TaskHelper
+	block := ManagedBlock{id: "path", content: "export PATH=/opt/bin:$PATH", comment: "#"}
+	if err := th.WriteBlock(link, block); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is no longer a symlink: %v", link, err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# mine\n") || !strings.Contains(string(data), block.content) {
+		t.Fatalf("block was not written into symlink target, got %q", data)
+	}
+}
+
+func TestGitCloneResolvesRefsOnRemote(t *testing.T) {
+	r := newGitRemote(t, "plugin")
+	first := git(t, r.work, "rev-parse", "HEAD")
+	// tag named like an abbreviated hash must resolve as tag
+	git(t, r.work, "tag", "deadbeef")
+	git(t, r.work, "push", "--quiet", "origin", "deadbeef")
+	writeFile(t, filepath.Join(r.work, "options.lua"), "-- options\n")
+	r.commit(t, "add options")
+
+	var th TaskHelper
+	dir := filepath.Join(t.TempDir(), "plugin")
+	if err := th.GitClone(r.remote, dir, GitCloneOptions{ref: "deadbeef", ensure: true}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, dir, "rev-parse", "HEAD"); got != first {
+		t.Fatalf("HEAD is %s, want tagged %s", got, first)
+	}
+	err := th.GitClone(r.remote, dir, GitCloneOptions{ref: first[:12], ensure: true}, false)
+	if err == nil || !strings.Contains(err.Error(), "full commit hash") {
+		t.Fatalf("expected abbreviated hash to be rejected, got %v", err)
+	}
+}
+
+func TestGitCloneKeepsLocalModifications(t *testing.T) {
+	r := newGitRemote(t, "plugin")
+	first := git(t, r.work, "rev-parse", "HEAD")
+	writeFile(t, filepath.Join(r.work, "init.lua"), "-- second\n")
+	second := r.commit(t, "second")
+
+	var th TaskHelper
+	dir := filepath.Join(t.TempDir(), "plugin")
+	if err := th.GitClone(r.remote, dir, GitCloneOptions{ref: first, ensure: true}, false); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "init.lua"), "-- local edit\n")
+	// same commit does not touch the checkout
+	if err := th.GitClone(r.remote, dir, GitCloneOptions{ref: first, ensure: true}, false); err != nil {
+		t.Fatal(err)
+	}
+	err := th.GitClone(r.remote, dir, GitCloneOptions{ref: second, ensure: true}, false)
+	if err == nil || !strings.Contains(err.Error(), "local modifications") {
+		t.Fatalf("expected checkout to stop on local modifications, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "init.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "-- local edit\n" {
+		t.Fatalf("local edit was discarded, got %q", data)
+	}
+}
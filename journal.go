@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type JournalAction string
+
+const (
+	// ActionPath marks file or directory created by a task.
+	ActionPath JournalAction = "path"
+	// ActionContent marks content appended to a file, Data holds the content.
+	ActionContent JournalAction = "content"
+	// ActionBlock marks managed block written to a file, Data holds the block id.
+	ActionBlock JournalAction = "block"
+	// ActionPackage marks system package installed by a task.
+	ActionPackage JournalAction = "package"
+	// ActionAptRepository marks apt repository added by AptRepositoryTask.
+	ActionAptRepository JournalAction = "apt-repository"
+	// ActionShell marks login shell change, Data holds the previous shell.
+	ActionShell JournalAction = "shell"
+)
+
+type JournalEntry struct {
+	RunID     string        `json:"run_id"`
+	Time      time.Time     `json:"time"`
+	Component string        `json:"component"`
+	Task      string        `json:"task"`
+	Action    JournalAction `json:"action"`
+	Target    string        `json:"target"`
+	Data      string        `json:"data,omitempty"`
+	IsSudo    bool          `json:"is_sudo,omitempty"`
+}
+
+// Journal records what tasks actually did during a run,
+// so it can be reverted later. Entries are stored as JSON lines.
+type Journal struct {
+	path      string
+	runID     string
+	component string
+}
+
+// OpenJournal creates journal directory if missing
+// and starts a new run identified by current time.
+func OpenJournal(path string) (*Journal, error) {
+	if err := FCreateDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return &Journal{
+		path:  path,
+		runID: FRunID(time.Now()),
+	}, nil
+}
+
+// FRunID formats run time with nanoseconds, so runs started within
+// the same second get own backups. IDs sort in the order of runs.
+func FRunID(now time.Time) string {
+	return fmt.Sprintf("%s-%09d", now.Format("20060102-150405"), now.Nanosecond())
+}
+
+// BackupRoot is the directory which holds backups of every run.
+func (j *Journal) BackupRoot() string {
+	return filepath.Join(filepath.Dir(j.path), "backups")
+}
+
+// Backup returns backup of current run,
+// nil journal results in nil backup which takes no snapshots.
+func (j *Journal) Backup() *Backup {
+	if j == nil {
+		return nil
+	}
+	return j.BackupOf(j.runID)
+}
+
+// BackupOf returns backup of given run.
+func (j *Journal) BackupOf(runID string) *Backup {
+	return &Backup{dir: filepath.Join(j.BackupRoot(), runID)}
+}
+
+// Scope returns journal which records entries under given component.
+func (j *Journal) Scope(component string) *Journal {
+	if j == nil {
+		return nil
+	}
+	scoped := *j
+	scoped.component = component
+	return &scoped
+}
+
+func (j *Journal) RunID() string {
+	if j == nil {
+		return ""
+	}
+	return j.runID
+}
+
+// Record appends entry to the journal file.
+// It is a no-op for nil journal, which keeps tasks usable without one.
+func (j *Journal) Record(e JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+	e.RunID = j.runID
+	e.Component = j.component
+	e.Time = time.Now()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %v", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal %s: %v", j.path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %v", err)
+	}
+	return nil
+}
+
+// Entries reads every recorded entry in the order of recording.
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %v", j.path, err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to decode journal entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal %s: %v", j.path, err)
+	}
+	return entries, nil
+}
+
+// Rewrite replaces journal content with given entries.
+func (j *Journal) Rewrite(entries []JournalEntry) error {
+	var data []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal entry: %v", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+	if err := os.WriteFile(j.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal %s: %v", j.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunIDIsUniqueWithinSecond(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 5, time.UTC)
+	first, second := FRunID(now), FRunID(now.Add(time.Millisecond))
+	if first == second || first > second {
+		t.Fatalf("run IDs %s and %s are not unique and ordered", first, second)
+	}
+	if first != "20250301-120000-000000005" {
+		t.Fatalf("unexpected run ID %s", first)
+	}
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	a, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Backup().Dir() == b.Backup().Dir() {
+		t.Fatalf("runs share backup directory %s", a.Backup().Dir())
+	}
+}
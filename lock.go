@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type LockEntry struct {
+	URL    string `json:"url"`
+	Ref    string `json:"ref,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// Lockfile pins every git source to resolved commit,
+// so the same workflow checks out the same code on every machine.
+// Previous version is kept next to it with .prev suffix for rollback.
+type Lockfile struct {
+	path    string
+	sources map[string]LockEntry
+	changed bool
+}
+
+// LoadLockfile reads lockfile, missing file results in empty lockfile.
+func LoadLockfile(path string) (*Lockfile, error) {
+	l := &Lockfile{path: path, sources: map[string]LockEntry{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %v", err)
+	}
+	if err := json.Unmarshal(data, &l.sources); err != nil {
+		return nil, fmt.Errorf("failed to decode lockfile %s: %v", path, err)
+	}
+	return l, nil
+}
+
+func (l *Lockfile) Path() string {
+	return l.path
+}
+
+func (l *Lockfile) PreviousPath() string {
+	return l.path + ".prev"
+}
+
+// Names returns locked source names in sorted order.
+func (l *Lockfile) Names() []string {
+	names := make([]string, 0, len(l.sources))
+	for name := range l.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (l *Lockfile) Get(name string) (LockEntry, bool) {
+	e, ok := l.sources[name]
+	return e, ok
+}
+
+// Commit returns locked commit of a source, if it is locked
+// with the same url and ref. Changed declaration is treated as unlocked.
+// It always reports unlocked for nil lockfile.
+func (l *Lockfile) Commit(name, url, ref string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	e, ok := l.sources[name]
+	if !ok || e.URL != url || e.Ref != ref {
+		return "", false
+	}
+	return e.Commit, true
+}
+
+// Set locks source to commit, it is a no-op for nil lockfile.
+func (l *Lockfile) Set(name string, e LockEntry) {
+	if l == nil {
+		return
+	}
+	if prev, ok := l.sources[name]; ok && prev == e {
+		return
+	}
+	l.sources[name] = e
+	l.changed = true
+}
+
+// Save writes lockfile if it was changed. With keepPrevious,
+// current file on disk is kept as previous version first.
+// It is a no-op for nil lockfile.
+func (l *Lockfile) Save(keepPrevious bool) error {
+	if l == nil || !l.changed {
+		return nil
+	}
+	if keepPrevious {
+		data, err := os.ReadFile(l.path)
+		if err == nil {
+			if err := os.WriteFile(l.PreviousPath(), data, 0644); err != nil {
+				return fmt.Errorf("failed to keep previous lockfile: %v", err)
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read lockfile: %v", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(l.sources, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %v", err)
+	}
+	if err := FCreateDir(filepath.Dir(l.path)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %v", err)
+	}
+	l.changed = false
+	return nil
+}
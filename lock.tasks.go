@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+type LockUpdateConfig struct {
+	lock *Lockfile
+	// cacheDir keeps bare mirrors of locked repositories,
+	// used to resolve refs and print changelog.
+	cacheDir string
+	// names limits update to listed sources, empty means all of them.
+	names []string
+}
+
+// LockUpdateTask bumps locked commits to the latest commit of each ref
+// and prints subjects of new commits. Previous lockfile is kept for rollback.
+type LockUpdateTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *LockUpdateTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(LockUpdateConfig)
+
+	if cfg.lock == nil {
+		return FPrefixError(t.Name, "lockfile is required")
+	}
+	if len(cfg.cacheDir) == 0 {
+		return FPrefixError(t.Name, "cache directory is empty")
+	}
+	for _, name := range cfg.names {
+		if _, ok := cfg.lock.Get(name); !ok {
+			return FPrefixError(t.Name, fmt.Sprintf("source '%s' is not locked", name))
+		}
+	}
+	return nil
+}
+
+func (t *LockUpdateTask) Run() error {
+	cfg, _ := t.Config.(LockUpdateConfig)
+
+	if err := FCreateDir(cfg.cacheDir); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, name := range cfg.lock.Names() {
+		if len(cfg.names) > 0 && !slices.Contains(cfg.names, name) {
+			continue
+		}
+		e, _ := cfg.lock.Get(name)
+		mirror := filepath.Join(cfg.cacheDir, name+".git")
+		if err := t.th.GitMirror(e.URL, mirror); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+
+		candidates := []string{"refs/heads/" + e.Ref, "refs/tags/" + e.Ref, e.Ref}
+		if len(e.Ref) == 0 {
+			candidates = []string{"HEAD"}
+		}
+		commit := ""
+		for _, c := range candidates {
+			if resolved, ok := t.th.GitRevParse(mirror, c); ok {
+				commit = resolved
+				break
+			}
+		}
+		if len(commit) == 0 {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to resolve ref '%s' of %s", e.Ref, name))
+		}
+
+		if commit == e.Commit {
+			fmt.Printf("%s: up to date at %s\n", name, FShortCommit(commit))
+			continue
+		}
+		fmt.Printf("%s: %s -> %s\n", name, FShortCommit(e.Commit), FShortCommit(commit))
+		changelog, err := t.th.GitLog(mirror, e.Commit, commit)
+		if err != nil {
+			fmt.Println("  changelog is not available, locked commit is not an ancestor")
+		}
+		for _, line := range changelog {
+			fmt.Println("  " + line)
+		}
+
+		e.Commit = commit
+		cfg.lock.Set(name, e)
+	}
+
+	if err := cfg.lock.Save(true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+type LockRollbackConfig struct {
+	lock *Lockfile
+}
+
+// LockRollbackTask swaps lockfile with its previous version,
+// so running it twice undoes the rollback.
+type LockRollbackTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *LockRollbackTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(LockRollbackConfig)
+
+	if cfg.lock == nil {
+		return FPrefixError(t.Name, "lockfile is required")
+	}
+	if err := t.vh.ValidatePath(cfg.lock.PreviousPath(), false); err != nil {
+		return FPrefixError(t.Name, "no previous lockfile to roll back to")
+	}
+	return nil
+}
+
+func (t *LockRollbackTask) Run() error {
+	cfg, _ := t.Config.(LockRollbackConfig)
+
+	prev, err := os.ReadFile(cfg.lock.PreviousPath())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	curr, err := os.ReadFile(cfg.lock.Path())
+	if err != nil && !os.IsNotExist(err) {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := os.WriteFile(cfg.lock.Path(), prev, 0644); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if curr == nil {
+		if err := os.Remove(cfg.lock.PreviousPath()); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		return nil
+	}
+	if err := os.WriteFile(cfg.lock.PreviousPath(), curr, 0644); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+type LSPInstallMethod string
+
+const (
+	// LSPGo installs source module with go install.
+	LSPGo LSPInstallMethod = "go"
+	// LSPNpm installs source package and extras with npm.
+	LSPNpm LSPInstallMethod = "npm"
+	// LSPGithub downloads asset from source repository releases.
+	LSPGithub LSPInstallMethod = "github"
+	// LSPPackage installs source as a distro package.
+	LSPPackage LSPInstallMethod = "package"
+)
+
+// LanguageServer describes how to install a language server
+// and which binaries it provides.
+type LanguageServer struct {
+	method LSPInstallMethod
+	// source is go module, npm package, github repository or distro package name.
+	source string
+	// version is the default version, it can be overridden with name@version.
+	version string
+	// extras are additional npm packages with versions, such as typescript.
+	extras []string
+	// asset is github release asset name, {version} is replaced with version.
+	// Supported formats are .tar.gz archives and single gzipped binaries.
+	asset string
+	// sha256 maps version to digest of its asset, versions without one
+	// are verified with the digest reported by GitHub releases API.
+	sha256 map[string]string
+	// binDir is the directory of binaries inside of extracted archive.
+	binDir   string
+	binaries []string
+}
+
+// LanguageServerRegistry is the registry keyed by nvim-lspconfig server names.
+var LanguageServerRegistry = map[string]LanguageServer{
+	"gopls": {
+		method:   LSPGo,
+		source:   "golang.org/x/tools/gopls",
+		version:  "v0.18.1",
+		binaries: []string{"gopls"},
+	},
+	"ts_ls": {
+		method:   LSPNpm,
+		source:   "typescript-language-server",
+		version:  "4.3.4",
+		extras:   []string{"typescript@5.8.2"},
+		binaries: []string{"typescript-language-server", "tsserver"},
+	},
+	"pyright": {
+		method:   LSPNpm,
+		source:   "pyright",
+		version:  "1.1.398",
+		binaries: []string{"pyright", "pyright-langserver"},
+	},
+	"rust_analyzer": {
+		method:   LSPGithub,
+		source:   "rust-lang/rust-analyzer",
+		version:  "2025-03-17",
+		asset:    "rust-analyzer-x86_64-unknown-linux-gnu.gz",
+		binaries: []string{"rust-analyzer"},
+	},
+	"lua_ls": {
+		method:   LSPGithub,
+		source:   "LuaLS/lua-language-server",
+		version:  "3.13.9",
+		asset:    "lua-language-server-{version}-linux-x64.tar.gz",
+		binDir:   "bin",
+		binaries: []string{"lua-language-server"},
+	},
+	"clangd": {
+		method:   LSPPackage,
+		source:   "clangd",
+		binaries: []string{"clangd"},
+	},
+	"bashls": {
+		method:   LSPNpm,
+		source:   "bash-language-server",
+		version:  "5.4.3",
+		binaries: []string{"bash-language-server"},
+	},
+	"yamlls": {
+		method:   LSPNpm,
+		source:   "yaml-language-server",
+		version:  "1.17.0",
+		binaries: []string{"yaml-language-server"},
+	},
+}
+
+// FParseServerSelection splits "name@version" into name and version,
+// version is empty if it is not specified.
+func FParseServerSelection(selection string) (string, string) {
+	name, version, _ := strings.Cut(selection, "@")
+	return name, version
+}
+
+// FServerPackages returns distro package names of selected servers,
+// which are installed as packages.
+func FServerPackages(selections []string) []string {
+	var packages []string
+	for _, selection := range selections {
+		name, _ := FParseServerSelection(selection)
+		if server, ok := LanguageServerRegistry[name]; ok && server.method == LSPPackage {
+			packages = append(packages, server.source)
+		}
+	}
+	return packages
+}
+
+// FRenderLspconfig renders Lua script with nvim-lspconfig setup call
+// for every server. Servers are sorted, so output is deterministic.
+func FRenderLspconfig(names []string) string {
+	var b strings.Builder
+	b.WriteString("-- Generated by autonvim from the language server registry, do not edit.\n")
+	b.WriteString("local ok, lspconfig = pcall(require, \"lspconfig\")\n")
+	b.WriteString("if not ok then\n")
+	b.WriteString("  return\n")
+	b.WriteString("end\n\n")
+	for _, name := range slices.Sorted(slices.Values(names)) {
+		b.WriteString("lspconfig." + name + ".setup({})\n")
+	}
+	return b.String()
+}
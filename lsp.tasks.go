@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LanguageServerConfig struct {
+	// path is autonvim managed directory, binaries are exposed in its bin directory
+	// and npm packages or release archives are kept in its servers directory.
+	path string
+	// servers are registry names, optionally with version as name@version.
+	servers []string
+	// apiURL is GitHub API base url, required for servers installed from releases.
+	apiURL string
+	tmpDir string
+	// goPath is go binary, required for servers installed with go install.
+	goPath string
+	// nodeBinDir contains node and npm, required for npm servers.
+	nodeBinDir string
+	// lspconfigPath is optional Lua file, which receives lspconfig setup calls.
+	lspconfigPath string
+	shrc          ShrcConfig
+	// isSudo is used for distro packages only.
+	isSudo bool
+}
+
+func (c LanguageServerConfig) BinDir() string {
+	return filepath.Join(c.path, "bin")
+}
+
+func (c LanguageServerConfig) ServerDir(name string) string {
+	return filepath.Join(c.path, "servers", name)
+}
+
+// LanguageServerTask installs selected language servers from the registry.
+type LanguageServerTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *LanguageServerTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(LanguageServerConfig)
+
+	if len(cfg.path) == 0 {
+		return FPrefixError(t.Name, "install path is empty")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, selection := range cfg.servers {
+		name, _ := FParseServerSelection(selection)
+		server, ok := LanguageServerRegistry[name]
+		if !ok {
+			return FPrefixError(t.Name, fmt.Sprintf("unknown language server '%s'", name))
+		}
+		switch server.method {
+		case LSPGo:
+			if err := t.vh.ValidatePath(cfg.goPath, false); err != nil {
+				return FPrefixError(t.Name, fmt.Sprintf("%s requires go: %v", name, err))
+			}
+		case LSPNpm:
+			if err := t.vh.ValidatePath(cfg.nodeBinDir, true); err != nil {
+				return FPrefixError(t.Name, fmt.Sprintf("%s requires node: %v", name, err))
+			}
+		case LSPGithub:
+			if err := t.vh.ValidateURL(cfg.apiURL); err != nil {
+				return FPrefixError(t.Name, fmt.Sprintf("%s requires github api: %v", name, err))
+			}
+		}
+	}
+	return nil
+}
+
+func (t *LanguageServerTask) Run() error {
+	cfg, _ := t.Config.(LanguageServerConfig)
+
+	isNewRoot, err := t.th.IsPathEmpty(cfg.path)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := FCreateDir(cfg.BinDir()); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if isNewRoot {
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.path}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+
+	var names []string
+	for _, selection := range cfg.servers {
+		name, version := FParseServerSelection(selection)
+		server := LanguageServerRegistry[name]
+		if len(version) == 0 {
+			version = server.version
+		}
+		if err := t.install(cfg, name, server, version); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to install %s: %v", name, err))
+		}
+		names = append(names, name)
+	}
+
+	files, err := t.th.WriteShellEnv(cfg.shrc, t.Journal.Backup())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: cfg.shrc.id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+
+	if len(cfg.lspconfigPath) > 0 {
+		if err := t.Journal.Backup().Snapshot(cfg.lspconfigPath, false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if err := FCreateDir(filepath.Dir(cfg.lspconfigPath)); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if err := t.th.WriteFile(cfg.lspconfigPath, FRenderLspconfig(names), "0644", false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.lspconfigPath}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+func (t *LanguageServerTask) install(cfg LanguageServerConfig, name string, server LanguageServer, version string) error {
+	serverDir := cfg.ServerDir(name)
+
+	switch server.method {
+	case LSPGo:
+		args := []string{"GOBIN=" + cfg.BinDir(), cfg.goPath, "install", server.source + "@" + version}
+		if _, err := FRunCommand("env", args, false); err != nil {
+			return err
+		}
+		return nil
+
+	case LSPNpm:
+		if err := t.Journal.Backup().Snapshot(serverDir, false); err != nil {
+			return err
+		}
+		if err := t.th.DeletePath(serverDir, false); err != nil {
+			return err
+		}
+		if err := FCreateDir(serverDir); err != nil {
+			return err
+		}
+		args := []string{
+			"PATH=" + cfg.nodeBinDir + ":" + os.Getenv("PATH"),
+			filepath.Join(cfg.nodeBinDir, "npm"), "install", "--prefix", serverDir, "--no-save",
+			server.source + "@" + version,
+		}
+		args = append(args, server.extras...)
+		if _, err := FRunCommand("env", args, false); err != nil {
+			return err
+		}
+		return t.linkBinaries(cfg, server, filepath.Join(serverDir, "node_modules/.bin"))
+
+	case LSPGithub:
+		asset, err := t.resolveAsset(cfg, name, server, version)
+		if err != nil {
+			return err
+		}
+		assetPath := filepath.Join(cfg.tmpDir, asset.Name)
+		if err := t.th.Download(asset.URL, assetPath, false); err != nil {
+			return err
+		}
+		expected, ok := server.sha256[version]
+		if !ok {
+			if expected, ok = FAssetSHA256(asset); !ok {
+				return fmt.Errorf("release %s has no checksum for %s, add its sha256 to the registry", version, asset.Name)
+			}
+		}
+		digest, err := t.th.FileSHA256(assetPath)
+		if err != nil {
+			return err
+		}
+		if expected = strings.ToLower(expected); digest != expected {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset.Name, expected, digest)
+		}
+		if err := t.Journal.Backup().Snapshot(serverDir, false); err != nil {
+			return err
+		}
+		if err := t.th.DeletePath(serverDir, false); err != nil {
+			return err
+		}
+		if err := FCreateDir(serverDir); err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(asset.Name, ".tar.gz") {
+			if err := t.th.ExtractTar(assetPath, serverDir, false); err != nil {
+				return err
+			}
+			return t.linkBinaries(cfg, server, filepath.Join(serverDir, server.binDir))
+		}
+		if _, err := FRunCommand("gzip", []string{"--decompress", "--force", assetPath}, false); err != nil {
+			return err
+		}
+		binPath := filepath.Join(serverDir, server.binaries[0])
+		if err := t.th.Move(strings.TrimSuffix(assetPath, ".gz"), binPath, false); err != nil {
+			return err
+		}
+		if err := t.th.UpdatePermission(binPath, "0755", false); err != nil {
+			return err
+		}
+		return t.linkBinaries(cfg, server, serverDir)
+
+	case LSPPackage:
+		task := InstallPackageTask{
+			BaseTask: BaseTask{
+				Name:    t.Name + " " + server.source,
+				Config:  InstallPackageConfig{name: server.source, version: version, isSudo: cfg.isSudo},
+				Journal: t.Journal,
+			},
+		}
+		if err := task.Validate(); err != nil {
+			return err
+		}
+		return task.Run()
+	}
+	return fmt.Errorf("unsupported install method %s", server.method)
+}
+
+// resolveAsset fetches release of version and returns its asset of the server.
+func (t *LanguageServerTask) resolveAsset(cfg LanguageServerConfig, name string, server LanguageServer, version string) (GithubAsset, error) {
+	releasePath := filepath.Join(cfg.tmpDir, "lsp-"+name+".json")
+	if err := t.th.Download(FReleaseURL(cfg.apiURL, server.source, version), releasePath, false); err != nil {
+		return GithubAsset{}, err
+	}
+	data, err := os.ReadFile(releasePath)
+	if err != nil {
+		return GithubAsset{}, fmt.Errorf("failed to read github release: %v", err)
+	}
+	release, err := FParseGithubRelease(data)
+	if err != nil {
+		return GithubAsset{}, err
+	}
+	return FMatchAsset(release.Assets, strings.ReplaceAll(server.asset, "{version}", version))
+}
+
+// linkBinaries exposes server binaries in bin directory with wrapper scripts,
+// which keep binaries working when they resolve files relative to themselves.
+func (t *LanguageServerTask) linkBinaries(cfg LanguageServerConfig, server LanguageServer, dir string) error {
+	for _, bin := range server.binaries {
+		target := filepath.Join(dir, bin)
+		if _, err := os.Stat(target); err != nil {
+			return fmt.Errorf("binary %s is missing: %v", bin, err)
+		}
+		wrapper := fmt.Sprintf("#!/bin/sh\nexec %s \"$@\"\n", FShellQuote(target))
+		if err := t.th.WriteFile(filepath.Join(cfg.BinDir(), bin), wrapper, "0755", false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,5 +1,79 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: autonvim [command]
+
+commands:
+  install                          run the example workflow (default)
+  uninstall [--purge] [component]  revert what was recorded in the journal
+  restore [run-id]                 restore backups taken during a run, lists runs if omitted
+  lock update [name...]            bump locked git commits and print changelog
+  lock rollback                    restore previous lockfile
+  dotfiles status                  show which dotfiles are linked, modified or missing
+  dotfiles sync [--push -m msg]    fast-forward dotfiles, optionally commit and push local changes
+  font check                       report whether the Nerd Font is known to fontconfig
+`
+
 func main() {
-	ExampleRun()
+	if len(os.Args) < 2 {
+		ExampleRun()
+		return
+	}
+
+	switch os.Args[1] {
+	case "install":
+		ExampleRun()
+	case "uninstall":
+		fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+		purge := fs.Bool("purge", false, "purge apt packages installed by autonvim")
+		fs.Parse(os.Args[2:])
+		check(ExampleUninstall(fs.Arg(0), *purge))
+	case "restore":
+		runID := ""
+		if len(os.Args) > 2 {
+			runID = os.Args[2]
+		}
+		check(ExampleRestore(runID))
+	case "lock":
+		if len(os.Args) > 2 && os.Args[2] == "update" {
+			check(ExampleLockUpdate(os.Args[3:]))
+			return
+		}
+		if len(os.Args) > 2 && os.Args[2] == "rollback" {
+			check(ExampleLockRollback())
+			return
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	case "dotfiles":
+		if len(os.Args) > 2 && os.Args[2] == "status" {
+			check(ExampleDotfilesStatus())
+			return
+		}
+		if len(os.Args) > 2 && os.Args[2] == "sync" {
+			fs := flag.NewFlagSet("dotfiles sync", flag.ExitOnError)
+			push := fs.Bool("push", false, "commit local changes and push them")
+			message := fs.String("m", "", "commit message used with --push")
+			fs.Parse(os.Args[3:])
+			check(ExampleDotfilesSync(*push, *message))
+			return
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	case "font":
+		if len(os.Args) > 2 && os.Args[2] == "check" {
+			check(ExampleFontCheck())
+			return
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// NeovimBuildTypes are accepted CMAKE_BUILD_TYPE values.
+var NeovimBuildTypes = []string{"Debug", "Release", "RelWithDebInfo", "MinSizeRel"}
+
+type BuildNeovimConfig struct {
+	// root is toolchains root, builds are installed side by side
+	// into root/neovim/<revision> with the same current symlink as releases.
+	root string
+	shrc ShrcConfig
+	// url and ref select git source, ref is locked as "neovim" in lock.
+	url string
+	ref string
+	// tarPath is local source tarball used instead of git source.
+	tarPath   string
+	buildType string
+	// cacheDir keeps git checkout of the source.
+	cacheDir string
+	tmpDir   string
+	lock     *Lockfile
+	isSudo   bool
+}
+
+// ToolDir returns directory of all installed Neovim versions.
+func (c BuildNeovimConfig) ToolDir() string {
+	return filepath.Join(c.root, "neovim")
+}
+
+// VersionDir returns install prefix of a source revision,
+// so rebuilding the same revision and build type is skipped.
+func (c BuildNeovimConfig) VersionDir(revision string) string {
+	return filepath.Join(c.ToolDir(), revision+"-"+c.buildType)
+}
+
+// BuildNeovimTask builds Neovim from source with cmake
+// and switches current toolchain symlink to the build.
+type BuildNeovimTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *BuildNeovimTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(BuildNeovimConfig)
+
+	if len(cfg.root) == 0 {
+		return FPrefixError(t.Name, "toolchains root is empty")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(cfg.cacheDir) == 0 {
+		return FPrefixError(t.Name, "cache directory is empty")
+	}
+	if (len(cfg.url) == 0) == (len(cfg.tarPath) == 0) {
+		return FPrefixError(t.Name, "either git url or source tarball is required")
+	}
+	if len(cfg.url) > 0 {
+		if err := t.vh.ValidateURL(cfg.url); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	} else if err := t.vh.ValidatePath(cfg.tarPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if !slices.Contains(NeovimBuildTypes, cfg.buildType) {
+		return FPrefixError(t.Name, fmt.Sprintf("unknown build type '%s', expected one of %v", cfg.buildType, NeovimBuildTypes))
+	}
+	if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *BuildNeovimTask) Run() error {
+	cfg, _ := t.Config.(BuildNeovimConfig)
+
+	srcDir, revision, err := t.source(cfg)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	isNewRoot, err := t.th.IsPathEmpty(cfg.ToolDir())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	versionDir := cfg.VersionDir(revision)
+	if _, err := os.Stat(filepath.Join(versionDir, "bin", "nvim")); err == nil {
+		slog.Info("revision is already built", "task_name", t.Name, "revision", revision)
+	} else if err := t.build(cfg, srcDir, versionDir); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := cfg.lock.Save(false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	if err := t.th.SwitchCurrent(cfg.ToolDir(), versionDir, cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	target := versionDir
+	if isNewRoot {
+		target = cfg.ToolDir()
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: target, IsSudo: cfg.isSudo}
+	if err := t.Journal.Record(entry); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	nvim := filepath.Join(cfg.ToolDir(), "current", "bin", "nvim")
+	if _, _, err := FRunCommandOutput(nvim, []string{"--version"}, false); err != nil {
+		return FPrefixError(t.Name, fmt.Sprintf("installed nvim does not run: %v", err))
+	}
+
+	files, err := t.th.WriteShellEnv(cfg.shrc, t.Journal.Backup())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		entry = JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: cfg.shrc.id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// source prepares source tree and returns its directory and revision.
+// Git sources are identified by commit, tarballs by their digest.
+func (t *BuildNeovimTask) source(cfg BuildNeovimConfig) (string, string, error) {
+	if len(cfg.tarPath) > 0 {
+		digest, err := t.th.FileSHA256(cfg.tarPath)
+		if err != nil {
+			return "", "", err
+		}
+		srcDir := filepath.Join(cfg.tmpDir, "neovim-src")
+		if err := FCreateDir(srcDir); err != nil {
+			return "", "", err
+		}
+		if err := t.th.ExtractArchive(cfg.tarPath, srcDir, 1, false); err != nil {
+			return "", "", err
+		}
+		return srcDir, "sha256-" + FShortCommit(digest), nil
+	}
+
+	srcDir := filepath.Join(cfg.cacheDir, "src")
+	if err := FCreateDir(cfg.cacheDir); err != nil {
+		return "", "", err
+	}
+	opts := GitCloneOptions{ref: cfg.ref, filter: "blob:none", ensure: true}
+	commit, err := t.th.GitLockedClone(cfg.url, srcDir, "neovim", opts, cfg.lock, false)
+	if err != nil {
+		return "", "", err
+	}
+	return srcDir, commit, nil
+}
+
+// build runs cmake for bundled dependencies and Neovim itself,
+// the same steps as make in Neovim repository, and installs into versionDir.
+func (t *BuildNeovimTask) build(cfg BuildNeovimConfig, srcDir, versionDir string) error {
+	depsDir := filepath.Join(srcDir, ".deps")
+	nvimDir := filepath.Join(srcDir, "build")
+	// stale build directories may point to another build type or prefix
+	for _, dir := range []string{depsDir, nvimDir} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clean %s: %v", dir, err)
+		}
+	}
+
+	buildType := "-DCMAKE_BUILD_TYPE=" + cfg.buildType
+	steps := [][]string{
+		{"-S", filepath.Join(srcDir, "cmake.deps"), "-B", depsDir, "-G", "Ninja", buildType},
+		{"--build", depsDir},
+		{"-S", srcDir, "-B", nvimDir, "-G", "Ninja", buildType, "-DCMAKE_INSTALL_PREFIX=" + versionDir},
+		{"--build", nvimDir},
+		{"--install", nvimDir},
+	}
+	for _, args := range steps {
+		if _, err := FRunCommand("cmake", args, false); err != nil {
+			// partial install must not be taken for a finished build
+			if err := os.RemoveAll(versionDir); err != nil {
+				slog.Error(err.Error())
+			}
+			return fmt.Errorf("failed to build neovim: %v", err)
+		}
+	}
+	slog.Info("neovim built", "task_name", t.Name, "prefix", versionDir)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NpmPackage is a package installed globally with npm at pinned version.
+type NpmPackage struct {
+	name    string
+	version string
+}
+
+// FNpmInstallCommand returns shell command, which installs packages globally.
+// Packages must be pinned, so reinstalling a node version gives the same tools.
+// It returns empty command for no packages.
+func FNpmInstallCommand(packages []NpmPackage) (string, error) {
+	if len(packages) == 0 {
+		return "", nil
+	}
+	args := []string{"npm", "install", "--global"}
+	for _, p := range packages {
+		if len(p.name) == 0 {
+			return "", fmt.Errorf("npm package name is empty")
+		}
+		if len(p.version) == 0 || p.version == "latest" {
+			return "", fmt.Errorf("npm package %s must be pinned to a version", p.name)
+		}
+		args = append(args, FShellQuote(p.name+"@"+p.version))
+	}
+	return strings.Join(args, " "), nil
+}
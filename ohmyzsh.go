@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ZshCustom is a third party plugin or theme cloned into $ZSH_CUSTOM.
+type ZshCustom struct {
+	name string
+	url  string
+	// ref is branch, tag or commit, empty for default branch.
+	ref   string
+	theme bool
+}
+
+// Dir returns checkout directory inside of Oh My Zsh custom directory.
+func (c ZshCustom) Dir(customDir string) string {
+	kind := "plugins"
+	if c.theme {
+		kind = "themes"
+	}
+	return filepath.Join(customDir, kind, c.name)
+}
+
+var zshPluginRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// FValidateZshPlugins rejects plugin names, which would break plugins=(...) list.
+func FValidateZshPlugins(plugins []string) error {
+	for _, p := range plugins {
+		if !zshPluginRe.MatchString(p) {
+			return fmt.Errorf("invalid zsh plugin name '%s'", p)
+		}
+	}
+	return nil
+}
+
+// FRenderOhMyZsh renders .zshrc block, which configures and loads Oh My Zsh.
+// Completion directories in fpath are added before Oh My Zsh runs compinit.
+func FRenderOhMyZsh(zshDir, theme string, plugins, fpath []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "export ZSH=%s\n", quoteEnv(ShellZsh, zshDir))
+	fmt.Fprintf(&b, "ZSH_THEME=%s\n", quoteEnv(ShellZsh, theme))
+	fmt.Fprintf(&b, "plugins=(%s)\n", strings.Join(plugins, " "))
+	for _, dir := range fpath {
+		fmt.Fprintf(&b, "fpath=(%s $fpath)\n", quoteEnv(ShellZsh, dir))
+	}
+	// checkout is pinned, updates go through lockfile
+	b.WriteString("zstyle ':omz:update' mode disabled\n")
+	b.WriteString("source \"$ZSH/oh-my-zsh.sh\"\n")
+	return b.String()
+}
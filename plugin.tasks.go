@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PluginSpec declares Neovim plugin installed as native package.
+type PluginSpec struct {
+	// name is the plugin directory, defaults to repository name.
+	name string
+	url  string
+	// ref is a branch, tag or commit, empty means default branch.
+	ref string
+	// opt places plugin into opt instead of start, so it is loaded with :packadd.
+	opt bool
+	// build is a shell command executed in plugin directory
+	// after plugin is installed or updated.
+	build string
+}
+
+func (p PluginSpec) Name() string {
+	if len(p.name) > 0 {
+		return p.name
+	}
+	return strings.TrimSuffix(filepath.Base(p.url), ".git")
+}
+
+func (p PluginSpec) Kind() string {
+	if p.opt {
+		return "opt"
+	}
+	return "start"
+}
+
+type NeovimPluginConfig struct {
+	// path is the package directory, such as ~/.config/nvim/pack/autonvim.
+	// Everything in its start and opt directories is managed by the task.
+	path     string
+	plugins  []PluginSpec
+	nvimPath string
+	// lock is optional, when set plugins are checked out at locked commits.
+	lock *Lockfile
+	// legacyPaths are plugin clones of previous installs outside of path,
+	// they are removed, so plugins are not loaded twice.
+	legacyPaths []string
+}
+
+// NeovimPluginTask installs, updates and removes plugins,
+// so package directory matches the declared list.
+type NeovimPluginTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *NeovimPluginTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(NeovimPluginConfig)
+
+	if len(cfg.path) == 0 {
+		return FPrefixError(t.Name, "package path is empty")
+	}
+	if err := t.vh.ValidatePath(cfg.nvimPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	names := make(map[string]bool, len(cfg.plugins))
+	for _, p := range cfg.plugins {
+		if err := t.vh.ValidateURL(p.url); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		name := p.Name()
+		if len(name) == 0 || name == "." || strings.Contains(name, "/") {
+			return FPrefixError(t.Name, fmt.Sprintf("invalid plugin name '%s'", name))
+		}
+		if names[name] {
+			return FPrefixError(t.Name, fmt.Sprintf("plugin '%s' is declared twice", name))
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+func (t *NeovimPluginTask) Run() error {
+	cfg, _ := t.Config.(NeovimPluginConfig)
+
+	declared := make(map[string]bool, len(cfg.plugins))
+	for _, p := range cfg.plugins {
+		dir := filepath.Join(cfg.path, p.Kind(), p.Name())
+		declared[dir] = true
+		if err := t.installPlugin(p, dir, cfg.lock); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if err := cfg.lock.Save(false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	for _, kind := range []string{"start", "opt"} {
+		entries, err := os.ReadDir(filepath.Join(cfg.path, kind))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		for _, e := range entries {
+			dir := filepath.Join(cfg.path, kind, e.Name())
+			if declared[dir] {
+				continue
+			}
+			if err := t.Journal.Backup().Snapshot(dir, false); err != nil {
+				return FPrefixError(t.Name, err.Error())
+			}
+			if err := t.th.DeletePath(dir, false); err != nil {
+				return FPrefixError(t.Name, err.Error())
+			}
+			slog.Info("plugin removed", "task_name", t.Name, "path", dir)
+		}
+	}
+
+	for _, dir := range cfg.legacyPaths {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if err := t.Journal.Backup().Snapshot(dir, false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if err := t.th.DeletePath(dir, false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		slog.Info("legacy plugin clone removed", "task_name", t.Name, "path", dir)
+	}
+
+	for dir := range declared {
+		docDir := filepath.Join(dir, "doc")
+		if _, err := os.Stat(docDir); err != nil {
+			continue
+		}
+		args := []string{"--headless", "-u", "NONE", "-c", "helptags " + docDir, "-c", "qa!"}
+		if _, err := FRunCommand(cfg.nvimPath, args, false); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to generate helptags for %s: %v", dir, err))
+		}
+	}
+	return nil
+}
+
+// installPlugin clones plugin or updates existing clone and runs
+// build command until it succeeds for checked out commit.
+func (t *NeovimPluginTask) installPlugin(p PluginSpec, dir string, lock *Lockfile) error {
+	prevCommit := ""
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	cloned := err == nil
+	if cloned {
+		prevCommit, _ = t.th.GitRevParse(dir, "HEAD")
+	} else if err := FCreateDir(filepath.Dir(dir)); err != nil {
+		return err
+	}
+
+	opts := GitCloneOptions{ref: p.ref, filter: "blob:none", submodules: true, ensure: true}
+	commit, err := t.th.GitLockedClone(p.url, dir, p.Name(), opts, lock, false)
+	if err != nil {
+		return err
+	}
+	if !cloned {
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dir}
+		if err := t.Journal.Record(entry); err != nil {
+			return err
+		}
+	}
+	if commit != prevCommit {
+		slog.Info("plugin installed", "task_name", t.Name, "plugin", p.Name(), "commit", commit)
+	}
+	if len(p.build) == 0 {
+		return nil
+	}
+
+	// marker lives in .git, so it does not show up as untracked file,
+	// failed or interrupted build leaves it missing and is retried
+	marker := filepath.Join(dir, ".git", "autonvim-build")
+	built := commit + "\n" + p.build + "\n"
+	if data, err := os.ReadFile(marker); err == nil && string(data) == built {
+		return nil
+	}
+	if err := t.th.DeletePath(marker, false); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("cd %s && %s", FShellQuote(dir), p.build)
+	if _, err := FRunCommand("/bin/sh", []string{"-c", cmd}, false); err != nil {
+		return fmt.Errorf("failed to build plugin %s: %v", p.Name(), err)
+	}
+	if err := os.WriteFile(marker, []byte(built), 0644); err != nil {
+		return fmt.Errorf("failed to record build of plugin %s: %v", p.Name(), err)
+	}
+	return nil
+}
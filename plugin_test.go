@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNeovimPluginRetriesFailedBuild(t *testing.T) {
+	r := newGitRemote(t, "fzf-native")
+	dir := t.TempDir()
+	builds := filepath.Join(dir, "builds")
+	plugin := PluginSpec{
+		name: "fzf-native",
+		url:  r.remote,
+		// fails until the flag file exists
+		build: "test -e " + FShellQuote(filepath.Join(dir, "ok")) + " && echo built >> " + FShellQuote(builds),
+	}
+	task := NeovimPluginTask{
+		BaseTask: BaseTask{
+			Name: "NeovimPluginTask",
+			Config: NeovimPluginConfig{
+				path:     filepath.Join(dir, "pack"),
+				plugins:  []PluginSpec{plugin},
+				nvimPath: "/bin/true",
+			},
+		},
+	}
+	if err := task.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Run(); err == nil || !strings.Contains(err.Error(), "failed to build plugin") {
+		t.Fatalf("expected build to fail, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "ok"), "")
+	for range 2 {
+		if err := task.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(builds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "built\n" {
+		t.Fatalf("expected one successful build at unchanged commit, got %q", data)
+	}
+}
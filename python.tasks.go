@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PipPackage is a package installed into editor tooling venv at pinned version.
+type PipPackage struct {
+	name    string
+	version string
+}
+
+type PythonToolchainConfig struct {
+	// interpreter is distro python, used when standaloneURL is empty.
+	interpreter string
+	// root is toolchains root, standalone builds are installed
+	// side by side into root/python with current symlink.
+	root string
+	// standaloneURL is install_only archive of python-build-standalone
+	// verified with standaloneSHA256, it replaces distro interpreter.
+	standaloneURL    string
+	standaloneSHA256 string
+	// venvDir is isolated venv for editor tooling.
+	venvDir  string
+	packages []PipPackage
+	// binaries are venv executables symlinked into binDir,
+	// venv bin itself is not put on PATH, so it does not shadow python.
+	binaries []string
+	binDir   string
+	// providerPath is Lua file, which receives g:python3_host_prog.
+	providerPath string
+	tmpDir       string
+	// shrc receives binDir on PATH in addition to its own env.
+	shrc ShrcConfig
+}
+
+// Spec returns toolchain spec of standalone build, version is archive name,
+// because python-build-standalone tags builds by release date as well.
+func (c PythonToolchainConfig) Spec() ToolchainSpec {
+	return ToolchainSpec{
+		name:    "python",
+		version: strings.TrimSuffix(filepath.Base(c.standaloneURL), ".tar.gz"),
+		url:     c.standaloneURL,
+		sha256:  c.standaloneSHA256,
+		strip:   1,
+		binDir:  "bin",
+	}
+}
+
+// VenvPython returns interpreter of editor tooling venv.
+func (c PythonToolchainConfig) VenvPython() string {
+	return filepath.Join(c.venvDir, "bin", "python")
+}
+
+// PythonToolchainTask makes sure python is present, installs editor
+// tooling into a venv and exposes it to the shell and Neovim provider.
+type PythonToolchainTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *PythonToolchainTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(PythonToolchainConfig)
+
+	if len(cfg.standaloneURL) > 0 {
+		if err := t.vh.ValidateURL(cfg.standaloneURL); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if len(cfg.root) == 0 || len(cfg.standaloneSHA256) == 0 {
+			return FPrefixError(t.Name, "standalone build requires toolchain root and checksum")
+		}
+		if !strings.HasSuffix(cfg.standaloneURL, ".tar.gz") {
+			return FPrefixError(t.Name, "standalone build must be a .tar.gz archive")
+		}
+	} else if len(cfg.interpreter) == 0 {
+		return FPrefixError(t.Name, "either distro interpreter or standalone build is required")
+	}
+	if len(cfg.venvDir) == 0 || len(cfg.binDir) == 0 {
+		return FPrefixError(t.Name, "venv and bin directories are required")
+	}
+	for _, p := range cfg.packages {
+		if len(p.version) == 0 {
+			return FPrefixError(t.Name, fmt.Sprintf("pip package %s must be pinned to a version", p.name))
+		}
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *PythonToolchainTask) Run() error {
+	cfg, _ := t.Config.(PythonToolchainConfig)
+
+	interpreter := cfg.interpreter
+	if len(cfg.standaloneURL) > 0 {
+		var err error
+		if interpreter, err = t.installStandalone(cfg); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if _, _, err := FRunCommandOutput(interpreter, []string{"--version"}, false); err != nil {
+		return FPrefixError(t.Name, fmt.Sprintf("python interpreter %s is not usable: %v", interpreter, err))
+	}
+
+	if _, err := os.Stat(cfg.VenvPython()); err != nil {
+		if err := FCreateDir(filepath.Dir(cfg.venvDir)); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if _, err := FRunCommand(interpreter, []string{"-m", "venv", cfg.venvDir}, false); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to create venv: %v", err))
+		}
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.venvDir}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if len(cfg.packages) > 0 {
+		args := []string{"-m", "pip", "install", "--quiet", "--disable-pip-version-check"}
+		for _, p := range cfg.packages {
+			args = append(args, p.name+"=="+p.version)
+		}
+		if _, err := FRunCommand(cfg.VenvPython(), args, false); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to install pip packages: %v", err))
+		}
+	}
+
+	if err := t.linkBinaries(cfg); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.writeProvider(cfg); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	shrc := cfg.shrc
+	shrc.env = append([]EnvChange{{kind: EnvPath, value: cfg.binDir}}, shrc.env...)
+	files, err := t.th.WriteShellEnv(shrc, t.Journal.Backup())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: shrc.id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// installStandalone installs verified standalone build as a toolchain
+// and returns its interpreter. Interpreter is not put on PATH.
+func (t *PythonToolchainTask) installStandalone(cfg PythonToolchainConfig) (string, error) {
+	config := ToolchainConfig{root: cfg.root, spec: cfg.Spec(), tmpDir: cfg.tmpDir}
+	task := ToolchainTask{
+		BaseTask: BaseTask{Name: t.Name, Config: config, Journal: t.Journal},
+	}
+	if err := task.Validate(); err != nil {
+		return "", err
+	}
+	if err := task.Run(); err != nil {
+		return "", err
+	}
+	return filepath.Join(config.CurrentDir(), "bin", "python3"), nil
+}
+
+func (t *PythonToolchainTask) linkBinaries(cfg PythonToolchainConfig) error {
+	isNewBinDir, err := t.th.IsPathEmpty(cfg.binDir)
+	if err != nil {
+		return err
+	}
+	if err := FCreateDir(cfg.binDir); err != nil {
+		return err
+	}
+	if isNewBinDir {
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.binDir}
+		if err := t.Journal.Record(entry); err != nil {
+			return err
+		}
+	}
+	for _, bin := range cfg.binaries {
+		target := filepath.Join(cfg.venvDir, "bin", bin)
+		if _, err := os.Stat(target); err != nil {
+			return fmt.Errorf("binary %s is missing in venv: %v", bin, err)
+		}
+		link := filepath.Join(cfg.binDir, bin)
+		if err := t.th.DeletePath(link, false); err != nil {
+			return err
+		}
+		if err := t.th.Symlink(target, link, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeProvider points Neovim python provider to the venv, pynvim lives there.
+func (t *PythonToolchainTask) writeProvider(cfg PythonToolchainConfig) error {
+	if len(cfg.providerPath) == 0 {
+		return nil
+	}
+	if err := t.Journal.Backup().Snapshot(cfg.providerPath, false); err != nil {
+		return err
+	}
+	if err := FCreateDir(filepath.Dir(cfg.providerPath)); err != nil {
+		return err
+	}
+	block := ManagedBlock{
+		id:      "python-provider",
+		content: "vim.g.python3_host_prog = " + FLuaString(cfg.VenvPython()),
+		comment: "--",
+	}
+	if err := t.th.WriteBlock(cfg.providerPath, block); err != nil {
+		return err
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: cfg.providerPath, Data: block.id}
+	return t.Journal.Record(entry)
+}
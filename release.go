@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// GithubRelease is a release returned by GitHub releases API.
+type GithubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []GithubAsset `json:"assets"`
+	// Message is set instead of release, e.g. when tag is not found.
+	Message string `json:"message"`
+}
+
+type GithubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	// Digest is sha256:<hex>, GitHub reports it for newer uploads.
+	Digest string `json:"digest"`
+}
+
+// ReleaseTool describes a CLI tool installed from GitHub release assets.
+type ReleaseTool struct {
+	// repo is GitHub repository as owner/name.
+	repo string
+	// tag is the default release tag, it can be overridden with name@tag.
+	tag string
+	// asset is glob pattern of the asset for OS and arch,
+	// e.g. fzf-*-linux_amd64.tar.gz. Archives and single binaries are supported.
+	asset string
+	// checksums is glob pattern of release checksum file. Releases
+	// without one are verified with sha256 of the asset, or with the digest
+	// reported by the API, which is missing for assets of older releases.
+	checksums string
+	// sha256 maps asset name to its digest, e.g. fd-v10.2.0-x86_64-unknown-linux-musl.tar.gz.
+	sha256   map[string]string
+	binaries []string
+}
+
+// ReleaseToolRegistry is the registry of CLI tools keyed by binary name.
+// Tools without release checksum file, such as fd, bat or delta, are added
+// with sha256 of the asset of their default tag, computed with sha256sum.
+var ReleaseToolRegistry = map[string]ReleaseTool{
+	"fzf": {
+		repo:      "junegunn/fzf",
+		tag:       "v0.60.3",
+		asset:     "fzf-*-linux_amd64.tar.gz",
+		checksums: "fzf_*_checksums.txt",
+		binaries:  []string{"fzf"},
+	},
+	"lazygit": {
+		repo:      "jesseduffield/lazygit",
+		tag:       "v0.48.0",
+		asset:     "lazygit_*_Linux_x86_64.tar.gz",
+		checksums: "checksums.txt",
+		binaries:  []string{"lazygit"},
+	},
+}
+
+// ReleaseFile is a man page or shell completion found in release archive.
+type ReleaseFile struct {
+	// section is man page section directory, e.g. man1, empty for completions.
+	section string
+	// shell is completion shell, empty for man pages.
+	shell Shell
+	// name is installed file name, e.g. _fd for zsh completion.
+	name string
+}
+
+var manPageRe = regexp.MustCompile(`^(.+)\.([1-9])(\.gz)?$`)
+
+// FParseGithubRelease parses response of GitHub releases API.
+func FParseGithubRelease(data []byte) (GithubRelease, error) {
+	var release GithubRelease
+	if err := json.Unmarshal(data, &release); err != nil {
+		return GithubRelease{}, fmt.Errorf("failed to parse github release: %v", err)
+	}
+	if len(release.TagName) == 0 {
+		return GithubRelease{}, fmt.Errorf("github release not found: %s", release.Message)
+	}
+	return release, nil
+}
+
+// FReleaseURL returns API url of release, the latest one if tag is empty.
+func FReleaseURL(apiURL, repo, tag string) string {
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	if len(tag) == 0 {
+		return fmt.Sprintf("%s/repos/%s/releases/latest", apiURL, repo)
+	}
+	return fmt.Sprintf("%s/repos/%s/releases/tags/%s", apiURL, repo, tag)
+}
+
+// FAssetSHA256 returns sha256 digest of asset reported by the API,
+// it is missing for assets uploaded before GitHub started to report them.
+func FAssetSHA256(asset GithubAsset) (string, bool) {
+	digest, ok := strings.CutPrefix(asset.Digest, "sha256:")
+	return strings.ToLower(digest), ok
+}
+
+// FMatchAsset returns the only asset matching glob pattern.
+func FMatchAsset(assets []GithubAsset, pattern string) (GithubAsset, error) {
+	var matches []GithubAsset
+	var names []string
+	for _, a := range assets {
+		names = append(names, a.Name)
+		if ok, _ := path.Match(pattern, a.Name); ok {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return GithubAsset{}, fmt.Errorf("no asset matches %s, available: %s", pattern, strings.Join(names, ", "))
+	}
+	return GithubAsset{}, fmt.Errorf("%d assets match %s", len(matches), pattern)
+}
+
+// FClassifyReleaseFile tells man pages and shell completions of binaries
+// apart from other files of release archive, such as README or LICENSE.
+func FClassifyReleaseFile(name string, binaries []string) (ReleaseFile, bool) {
+	if m := manPageRe.FindStringSubmatch(name); m != nil && slices.Contains(binaries, m[1]) {
+		return ReleaseFile{section: "man" + m[2], name: name}, true
+	}
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	switch {
+	case ext == ".bash" && slices.Contains(binaries, stem):
+		return ReleaseFile{shell: ShellBash, name: stem}, true
+	case ext == ".fish" && slices.Contains(binaries, stem):
+		return ReleaseFile{shell: ShellFish, name: name}, true
+	case ext == ".zsh" && slices.Contains(binaries, stem):
+		return ReleaseFile{shell: ShellZsh, name: "_" + stem}, true
+	case ext == "" && strings.HasPrefix(name, "_") && slices.Contains(binaries, name[1:]):
+		return ReleaseFile{shell: ShellZsh, name: name}, true
+	}
+	return ReleaseFile{}, false
+}
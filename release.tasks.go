@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type ReleaseToolConfig struct {
+	// apiURL is GitHub API base url, e.g. https://api.github.com or a mirror.
+	apiURL string
+	// tools are registry names, optionally with tag as name@tag.
+	tools  []string
+	binDir string
+	// manDir receives man pages in section directories, e.g. man1.
+	manDir string
+	// completionDirs are completion directories per shell,
+	// completions for shells without one are skipped.
+	completionDirs map[Shell]string
+	tmpDir         string
+	// shrc receives binDir on PATH in addition to its own env.
+	shrc ShrcConfig
+}
+
+// ReleaseToolTask installs CLI tools from verified GitHub release assets
+// together with man pages and shell completions shipped in them.
+type ReleaseToolTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *ReleaseToolTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(ReleaseToolConfig)
+
+	if err := t.vh.ValidateURL(cfg.apiURL); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(cfg.binDir) == 0 || len(cfg.manDir) == 0 {
+		return FPrefixError(t.Name, "bin and man directories are required")
+	}
+	for _, selection := range cfg.tools {
+		name, _ := FParseServerSelection(selection)
+		tool, ok := ReleaseToolRegistry[name]
+		if !ok {
+			return FPrefixError(t.Name, fmt.Sprintf("unknown release tool '%s'", name))
+		}
+		if len(tool.binaries) == 0 {
+			return FPrefixError(t.Name, fmt.Sprintf("release tool '%s' has no binaries", name))
+		}
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *ReleaseToolTask) Run() error {
+	cfg, _ := t.Config.(ReleaseToolConfig)
+
+	if err := FCreateDir(cfg.binDir); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, selection := range cfg.tools {
+		name, tag := FParseServerSelection(selection)
+		tool := ReleaseToolRegistry[name]
+		if len(tag) == 0 {
+			tag = tool.tag
+		}
+		if err := t.install(cfg, name, tool, tag); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to install %s: %v", name, err))
+		}
+	}
+
+	shrc := cfg.shrc
+	shrc.env = append([]EnvChange{{kind: EnvPath, value: cfg.binDir}}, shrc.env...)
+	files, err := t.th.WriteShellEnv(shrc, t.Journal.Backup())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: shrc.id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// install resolves release, downloads and verifies the asset
+// and installs binaries, man pages and completions found in it.
+func (t *ReleaseToolTask) install(cfg ReleaseToolConfig, name string, tool ReleaseTool, tag string) error {
+	release, err := t.resolve(cfg, name, tool, tag)
+	if err != nil {
+		return err
+	}
+	asset, err := FMatchAsset(release.Assets, tool.asset)
+	if err != nil {
+		return err
+	}
+	assetPath := filepath.Join(cfg.tmpDir, asset.Name)
+	if err := t.th.Download(asset.URL, assetPath, false); err != nil {
+		return err
+	}
+	if err := t.verify(cfg, tool, release, asset, assetPath); err != nil {
+		return err
+	}
+
+	workDir := filepath.Join(cfg.tmpDir, "release-"+name)
+	if err := t.th.DeletePath(workDir, false); err != nil {
+		return err
+	}
+	if err := FCreateDir(workDir); err != nil {
+		return err
+	}
+	switch {
+	case strings.Contains(asset.Name, ".tar."), strings.HasSuffix(asset.Name, ".tgz"):
+		err = t.th.ExtractArchive(assetPath, workDir, 0, false)
+	case strings.HasSuffix(asset.Name, ".zip"):
+		err = t.th.ExtractZip(assetPath, workDir, false)
+	case len(tool.binaries) == 1:
+		err = t.th.Copy(assetPath, filepath.Join(workDir, tool.binaries[0]), false)
+	default:
+		err = fmt.Errorf("asset %s is not an archive, but %d binaries are expected", asset.Name, len(tool.binaries))
+	}
+	if err != nil {
+		return err
+	}
+
+	// destination path to source path
+	files := map[string]string{}
+	err = filepath.WalkDir(workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if slices.Contains(tool.binaries, d.Name()) {
+			files[filepath.Join(cfg.binDir, d.Name())] = path
+		} else if f, ok := FClassifyReleaseFile(d.Name(), tool.binaries); ok {
+			if len(f.section) > 0 {
+				files[filepath.Join(cfg.manDir, f.section, f.name)] = path
+			} else if dir, ok := cfg.completionDirs[f.shell]; ok {
+				files[filepath.Join(dir, f.name)] = path
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search release files: %v", err)
+	}
+	for _, bin := range tool.binaries {
+		if _, ok := files[filepath.Join(cfg.binDir, bin)]; !ok {
+			return fmt.Errorf("binary %s is missing in %s", bin, asset.Name)
+		}
+	}
+
+	for _, dst := range slices.Sorted(maps.Keys(files)) {
+		src := files[dst]
+		perm := "0644"
+		if filepath.Dir(dst) == cfg.binDir {
+			perm = "0755"
+		}
+		if err := t.Journal.Backup().Snapshot(dst, false); err != nil {
+			return err
+		}
+		if err := t.th.DeletePath(dst, false); err != nil {
+			return err
+		}
+		if err := FCreateDir(filepath.Dir(dst)); err != nil {
+			return err
+		}
+		if err := t.th.Copy(src, dst, false); err != nil {
+			return err
+		}
+		if err := t.th.UpdatePermission(dst, perm, false); err != nil {
+			return err
+		}
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dst}
+		if err := t.Journal.Record(entry); err != nil {
+			return err
+		}
+	}
+	slog.Info("release tool installed", "task_name", t.Name, "name", name, "tag", release.TagName, "files", len(files))
+	return nil
+}
+
+// resolve fetches release of tag, the latest one if tag is empty.
+func (t *ReleaseToolTask) resolve(cfg ReleaseToolConfig, name string, tool ReleaseTool, tag string) (GithubRelease, error) {
+	releasePath := filepath.Join(cfg.tmpDir, "release-"+name+".json")
+	if err := t.th.Download(FReleaseURL(cfg.apiURL, tool.repo, tag), releasePath, false); err != nil {
+		return GithubRelease{}, err
+	}
+	data, err := os.ReadFile(releasePath)
+	if err != nil {
+		return GithubRelease{}, fmt.Errorf("failed to read github release: %v", err)
+	}
+	return FParseGithubRelease(data)
+}
+
+// verify checks asset against release checksum file, registry digest
+// or digest reported by the API, in this order.
+func (t *ReleaseToolTask) verify(cfg ReleaseToolConfig, tool ReleaseTool, release GithubRelease, asset GithubAsset, assetPath string) error {
+	var expected string
+	if len(tool.checksums) > 0 {
+		checksums, err := FMatchAsset(release.Assets, tool.checksums)
+		if err != nil {
+			return err
+		}
+		sumsPath := filepath.Join(cfg.tmpDir, checksums.Name)
+		if err := t.th.Download(checksums.URL, sumsPath, false); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(sumsPath)
+		if err != nil {
+			return fmt.Errorf("failed to read checksums: %v", err)
+		}
+		var ok bool
+		if expected, ok = FParseChecksums(string(data))[asset.Name]; !ok {
+			return fmt.Errorf("%s does not list %s", checksums.Name, asset.Name)
+		}
+	} else if digest, ok := tool.sha256[asset.Name]; ok {
+		expected = strings.ToLower(digest)
+	} else if digest, ok := FAssetSHA256(asset); ok {
+		expected = digest
+	} else {
+		return fmt.Errorf("release %s has no checksum for %s, add its sha256 to the registry", release.TagName, asset.Name)
+	}
+
+	digest, err := t.th.FileSHA256(assetPath)
+	if err != nil {
+		return err
+	}
+	if digest != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset.Name, expected, digest)
+	}
+	return nil
+}
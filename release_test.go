@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReleaseVerifyFallsBackToRegistryDigest(t *testing.T) {
+	dir := t.TempDir()
+	assetPath := filepath.Join(dir, "fd.tar.gz")
+	writeFile(t, assetPath, "fd")
+	var th TaskHelper
+	digest, err := th.FileSHA256(assetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := ReleaseToolTask{BaseTask: BaseTask{Name: "ReleaseToolTask"}}
+	cfg := ReleaseToolConfig{tmpDir: dir}
+	release := GithubRelease{TagName: "v10.2.0"}
+	asset := GithubAsset{Name: "fd.tar.gz"}
+
+	err = task.verify(cfg, ReleaseTool{}, release, asset, assetPath)
+	if err == nil || !strings.Contains(err.Error(), "no checksum") {
+		t.Fatalf("expected asset without checksum to be rejected, got %v", err)
+	}
+	tool := ReleaseTool{sha256: map[string]string{"fd.tar.gz": strings.ToUpper(digest)}}
+	if err := task.verify(cfg, tool, release, asset, assetPath); err != nil {
+		t.Fatal(err)
+	}
+	tool.sha256["fd.tar.gz"] = strings.Repeat("0", 64)
+	err = task.verify(cfg, tool, release, asset, assetPath)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected registry digest to be checked, got %v", err)
+	}
+}
+
+func TestReleaseToolRegistryIsVerifiable(t *testing.T) {
+	for name, tool := range ReleaseToolRegistry {
+		if len(tool.checksums) > 0 {
+			continue
+		}
+		if len(tool.sha256) == 0 {
+			t.Errorf("%s %s has neither checksums file nor sha256 of its asset", name, tool.tag)
+		}
+		for asset := range tool.sha256 {
+			if !strings.Contains(asset, tool.tag) && !strings.Contains(asset, strings.TrimPrefix(tool.tag, "v")) {
+				t.Errorf("%s sha256 of %s is not for default tag %s", name, asset, tool.tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CargoTool is a crate installed with cargo install at pinned version.
+type CargoTool struct {
+	name    string
+	version string
+}
+
+type RustToolchainConfig struct {
+	// toolchain is rustup toolchain, e.g. 1.85.0 or stable.
+	toolchain  string
+	components []string
+	cargoHome  string
+	rustupHome string
+	// rustupInitURL is verified with checksum published next to it.
+	rustupInitURL string
+	// archivePath is pre-fetched standalone toolchain archive for offline use,
+	// it replaces rustup and is installed side by side into root/rust
+	// with current symlink, the same layout as other toolchains.
+	archivePath string
+	root        string
+	tools       []CargoTool
+	tmpDir      string
+	// shrc receives cargo environment in addition to its own env.
+	shrc ShrcConfig
+}
+
+// CurrentDir returns symlink of the active standalone toolchain.
+func (c RustToolchainConfig) CurrentDir() string {
+	return filepath.Join(c.root, "rust", "current")
+}
+
+// BinDir returns directory with cargo and rustc.
+func (c RustToolchainConfig) BinDir() string {
+	if len(c.archivePath) > 0 {
+		return filepath.Join(c.CurrentDir(), "bin")
+	}
+	return filepath.Join(c.cargoHome, "bin")
+}
+
+// Env returns shell environment of the toolchain. Rustup writes
+// env scripts into cargo home, so they are sourced instead of PATH changes.
+func (c RustToolchainConfig) Env() []EnvChange {
+	env := []EnvChange{
+		{kind: EnvVar, name: "CARGO_HOME", value: c.cargoHome},
+	}
+	if len(c.archivePath) > 0 {
+		return append(env,
+			EnvChange{kind: EnvPath, value: c.BinDir()},
+			EnvChange{kind: EnvPath, value: filepath.Join(c.cargoHome, "bin")},
+		)
+	}
+	return append(env,
+		EnvChange{kind: EnvVar, name: "RUSTUP_HOME", value: c.rustupHome},
+		EnvChange{kind: EnvSource, value: filepath.Join(c.cargoHome, "env"), shells: []Shell{ShellBash, ShellZsh, ShellPOSIX}},
+		EnvChange{kind: EnvSource, value: filepath.Join(c.cargoHome, "env.fish"), shells: []Shell{ShellFish}},
+	)
+}
+
+// commandEnv returns env arguments, so cargo and rustup
+// run against configured homes without sourced environment.
+func (c RustToolchainConfig) commandEnv() []string {
+	return []string{
+		"CARGO_HOME=" + c.cargoHome,
+		"RUSTUP_HOME=" + c.rustupHome,
+		"PATH=" + c.BinDir() + ":" + os.Getenv("PATH"),
+	}
+}
+
+// RustToolchainTask installs Rust with rustup or from standalone archive,
+// selected components and cargo tools at pinned versions.
+type RustToolchainTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *RustToolchainTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(RustToolchainConfig)
+
+	if (len(cfg.rustupInitURL) == 0) == (len(cfg.archivePath) == 0) {
+		return FPrefixError(t.Name, "either rustup-init url or standalone archive is required")
+	}
+	if len(cfg.rustupInitURL) > 0 {
+		if err := t.vh.ValidateURL(cfg.rustupInitURL); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if len(cfg.toolchain) == 0 || len(cfg.rustupHome) == 0 {
+			return FPrefixError(t.Name, "rustup requires toolchain and RUSTUP_HOME")
+		}
+	} else {
+		if err := t.vh.ValidatePath(cfg.archivePath, false); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if len(cfg.root) == 0 {
+			return FPrefixError(t.Name, "standalone archive requires toolchain root")
+		}
+	}
+	if len(cfg.cargoHome) == 0 {
+		return FPrefixError(t.Name, "CARGO_HOME is required")
+	}
+	for _, tool := range cfg.tools {
+		if len(tool.version) == 0 {
+			return FPrefixError(t.Name, fmt.Sprintf("cargo tool %s must be pinned to a version", tool.name))
+		}
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
+}
+
+func (t *RustToolchainTask) Run() error {
+	cfg, _ := t.Config.(RustToolchainConfig)
+
+	isNewCargoHome, err := t.th.IsPathEmpty(cfg.cargoHome)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(cfg.archivePath) > 0 {
+		err = t.installStandalone(cfg)
+	} else {
+		err = t.installRustup(cfg)
+	}
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if isNewCargoHome {
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.cargoHome}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+
+	for _, tool := range cfg.tools {
+		args := append(cfg.commandEnv(),
+			filepath.Join(cfg.BinDir(), "cargo"), "install", "--locked", tool.name, "--version", tool.version)
+		if _, err := FRunCommand("env", args, false); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("failed to install %s: %v", tool.name, err))
+		}
+	}
+
+	shrc := cfg.shrc
+	shrc.env = append(cfg.Env(), shrc.env...)
+	files, err := t.th.WriteShellEnv(shrc, t.Journal.Backup())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: shrc.id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// installRustup runs verified rustup-init on first install,
+// later runs install toolchain with existing rustup.
+func (t *RustToolchainTask) installRustup(cfg RustToolchainConfig) error {
+	rustup := filepath.Join(cfg.cargoHome, "bin", "rustup")
+	isNewRustupHome, err := t.th.IsPathEmpty(cfg.rustupHome)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if _, err := os.Stat(rustup); err == nil {
+		args = append(cfg.commandEnv(), rustup, "toolchain", "install", cfg.toolchain, "--profile", "minimal")
+	} else {
+		initPath := filepath.Join(cfg.tmpDir, "rustup-init")
+		if err := t.downloadRustupInit(cfg, initPath); err != nil {
+			return err
+		}
+		args = append(cfg.commandEnv(), initPath, "-y", "--no-modify-path",
+			"--default-toolchain", cfg.toolchain, "--profile", "minimal")
+	}
+	if len(cfg.components) > 0 {
+		args = append(args, "--component", strings.Join(cfg.components, ","))
+	}
+	if _, err := FRunCommand("env", args, false); err != nil {
+		return fmt.Errorf("failed to install rust toolchain %s: %v", cfg.toolchain, err)
+	}
+	args = append(cfg.commandEnv(), rustup, "default", cfg.toolchain)
+	if _, err := FRunCommand("env", args, false); err != nil {
+		return fmt.Errorf("failed to set default toolchain: %v", err)
+	}
+
+	if isNewRustupHome {
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.rustupHome}
+		if err := t.Journal.Record(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *RustToolchainTask) downloadRustupInit(cfg RustToolchainConfig, initPath string) error {
+	sumPath := initPath + ".sha256"
+	if err := t.th.Download(cfg.rustupInitURL+".sha256", sumPath, false); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(sumPath)
+	if err != nil {
+		return fmt.Errorf("failed to read rustup-init checksum: %v", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("rustup-init checksum is empty")
+	}
+
+	if err := t.th.Download(cfg.rustupInitURL, initPath, false); err != nil {
+		return err
+	}
+	digest, err := t.th.FileSHA256(initPath)
+	if err != nil {
+		return err
+	}
+	if digest != strings.ToLower(fields[0]) {
+		return fmt.Errorf("checksum mismatch for rustup-init: expected %s, got %s", fields[0], digest)
+	}
+	return t.th.UpdatePermission(initPath, "0755", false)
+}
+
+// installStandalone runs install.sh of standalone archive with selected
+// components. Archive may name them with -preview suffix, e.g. rustfmt-preview.
+func (t *RustToolchainTask) installStandalone(cfg RustToolchainConfig) error {
+	name := strings.TrimSuffix(filepath.Base(cfg.archivePath), ".tar.gz")
+	toolDir := filepath.Join(cfg.root, "rust")
+	versionDir := filepath.Join(toolDir, name)
+
+	isNewRoot, err := t.th.IsPathEmpty(toolDir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(versionDir, "bin", "cargo")); err == nil {
+		slog.Info("rust is already installed", "task_name", t.Name, "path", versionDir)
+	} else {
+		installerDir := filepath.Join(cfg.tmpDir, name)
+		if err := FCreateDir(installerDir); err != nil {
+			return err
+		}
+		if err := t.th.ExtractArchive(cfg.archivePath, installerDir, 1, false); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(installerDir, "components"))
+		if err != nil {
+			return fmt.Errorf("failed to read archive components: %v", err)
+		}
+		components, err := FSelectRustComponents(strings.Fields(string(data)), cfg.components)
+		if err != nil {
+			return err
+		}
+		args := []string{
+			filepath.Join(installerDir, "install.sh"),
+			"--prefix=" + versionDir,
+			"--components=" + strings.Join(components, ","),
+			"--disable-ldconfig",
+		}
+		if _, err := FRunCommand("sh", args, false); err != nil {
+			return fmt.Errorf("failed to install rust from archive: %v", err)
+		}
+	}
+
+	if err := t.th.SwitchCurrent(toolDir, versionDir, false); err != nil {
+		return err
+	}
+	target := versionDir
+	if isNewRoot {
+		target = toolDir
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: target}
+	return t.Journal.Record(entry)
+}
+
+// FSelectRustComponents maps requested components to names available
+// in standalone archive. Compiler, standard library and cargo are always selected.
+func FSelectRustComponents(available, requested []string) ([]string, error) {
+	var selected []string
+	for _, c := range available {
+		if c == "rustc" || c == "cargo" || strings.HasPrefix(c, "rust-std-") {
+			selected = append(selected, c)
+		}
+	}
+	for _, c := range requested {
+		switch {
+		case slices.Contains(available, c):
+			selected = append(selected, c)
+		case slices.Contains(available, c+"-preview"):
+			selected = append(selected, c+"-preview")
+		default:
+			return nil, fmt.Errorf("rust component %s is not in archive, available: %s", c, strings.Join(available, ", "))
+		}
+	}
+	return slices.Compact(selected), nil
+}
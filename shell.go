@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type Shell string
+
+const (
+	ShellBash  Shell = "bash"
+	ShellZsh   Shell = "zsh"
+	ShellFish  Shell = "fish"
+	ShellPOSIX Shell = "sh"
+)
+
+var Shells = []Shell{ShellBash, ShellZsh, ShellFish, ShellPOSIX}
+
+// RcPath returns startup file which is used for environment of given shell.
+func (s Shell) RcPath(homePath string) string {
+	switch s {
+	case ShellBash:
+		return filepath.Join(homePath, ".bashrc")
+	case ShellZsh:
+		return filepath.Join(homePath, ".zshrc")
+	case ShellFish:
+		return filepath.Join(homePath, ".config/fish/config.fish")
+	default:
+		return filepath.Join(homePath, ".profile")
+	}
+}
+
+type EnvKind string
+
+const (
+	// EnvPath prepends value to PATH, unless it is already there.
+	EnvPath EnvKind = "path"
+	// EnvVar exports variable name with value.
+	EnvVar EnvKind = "var"
+	// EnvSource sources value as a file, if it exists.
+	EnvSource EnvKind = "source"
+)
+
+// EnvChange is a single shell environment change, rendered
+// into syntax of each shell. Values are double quoted,
+// so variables such as $HOME are expanded by the shell.
+type EnvChange struct {
+	kind  EnvKind
+	name  string
+	value string
+	// shells limits the change to listed shells, empty means all of them.
+	// Useful for scripts such as nvm.sh that only work in some shells.
+	shells []Shell
+}
+
+func (e EnvChange) AppliesTo(s Shell) bool {
+	return len(e.shells) == 0 || slices.Contains(e.shells, s)
+}
+
+// FRenderEnv renders environment changes for given shell.
+func FRenderEnv(s Shell, changes []EnvChange) string {
+	var b strings.Builder
+	for _, e := range changes {
+		if !e.AppliesTo(s) {
+			continue
+		}
+		value := quoteEnv(s, e.value)
+		if s == ShellFish {
+			switch e.kind {
+			case EnvPath:
+				fmt.Fprintf(&b, "contains -- %s $PATH; or set -gx PATH %s $PATH\n", value, value)
+			case EnvVar:
+				fmt.Fprintf(&b, "set -gx %s %s\n", e.name, value)
+			case EnvSource:
+				fmt.Fprintf(&b, "test -s %s; and source %s\n", value, value)
+			}
+			continue
+		}
+		switch e.kind {
+		case EnvPath:
+			fmt.Fprintf(&b, "case \":$PATH:\" in *:%s:*) ;; *) export PATH=%s:\"$PATH\" ;; esac\n", value, value)
+		case EnvVar:
+			fmt.Fprintf(&b, "export %s=%s\n", e.name, value)
+		case EnvSource:
+			fmt.Fprintf(&b, "[ -s %s ] && . %s\n", value, value)
+		}
+	}
+	return b.String()
+}
+
+func quoteEnv(s Shell, value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+	if s == ShellFish {
+		r = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	}
+	return `"` + r.Replace(value) + `"`
+}
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 )
 
 type BaseTask struct {
 	Name   string
 	Config any
+	// Journal is optional, when set tasks record what they did,
+	// so it can be reverted by UninstallTask.
+	Journal *Journal
 }
 
 func (t BaseTask) Initialize() {
@@ -26,9 +34,10 @@ type Task interface {
 }
 
 type InstallPackageConfig struct {
-	name   string
-	path   string
-	isSudo bool
+	name    string
+	path    string
+	version string
+	isSudo  bool
 }
 
 type InstallPackageTask struct {
@@ -50,179 +59,446 @@ func (t *InstallPackageTask) Validate() error {
 	if err := t.vh.ValidatePath(cfg.path, false); len(cfg.path) > 0 && err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+	if _, err := FParseVersionConstraint(cfg.version); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
 	return nil
 }
 
 func (t *InstallPackageTask) Run() error {
 	cfg, _ := t.Config.(InstallPackageConfig)
-	isInstalled, err := t.th.IsPackageInstalled(cfg.name, cfg.isSudo)
+	constraint, _ := FParseVersionConstraint(cfg.version)
+
+	installed, isInstalled, err := t.th.InstalledPackageVersion(cfg.name, cfg.isSudo)
 	if err != nil {
 		return FPrefixError(t.Name, "failed to check package installation")
 	}
-	if isInstalled {
-		promptAsk := fmt.Sprintf("Package '%s' is already installed. Would you like to install/update it? (y/n): ", cfg.name)
-		if !FPrompt(promptAsk) {
-			return nil
-		}
+	if isInstalled && constraint.Satisfies(installed) {
+		slog.Info("package is already installed", "task_name", t.Name, "version", installed, "constraint", constraint.String())
+		return nil
 	}
+
 	var identifier string
 	if len(cfg.path) > 0 {
 		identifier = cfg.path
+	} else if constraint.IsExact() {
+		identifier = cfg.name + "=" + constraint.version
 	} else {
 		identifier = cfg.name
 	}
 
 	cmd := "apt"
-	args := []string{"install", "--yes", identifier}
+	args := []string{"install", "--yes"}
+	if isInstalled {
+		args = append(args, "--allow-downgrades")
+	}
+	args = append(args, identifier)
 
 	if _, err := FRunCommand(cmd, args, cfg.isSudo); err != nil {
 		return FPrefixError(t.Name, "failed to install the package")
 	}
+	if !isInstalled {
+		entry := JournalEntry{Task: t.Name, Action: ActionPackage, Target: cfg.name, IsSudo: cfg.isSudo}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+
+	if constraint.IsEmpty() {
+		return nil
+	}
+	installed, isInstalled, err = t.th.InstalledPackageVersion(cfg.name, cfg.isSudo)
+	if err != nil {
+		return FPrefixError(t.Name, "failed to check package installation")
+	}
+	if !isInstalled || !constraint.Satisfies(installed) {
+		return FPrefixError(t.Name, fmt.Sprintf("installed version '%s' does not satisfy %s", installed, constraint.String()))
+	}
 
 	return nil
 }
 
-type NeovimLSPConfig struct {
-	path   Path
-	url    string
-	isSudo bool
+const (
+	AptKeyringsDir string = "/etc/apt/keyrings"
+	AptSourcesDir  string = "/etc/apt/sources.list.d"
+)
+
+type AptRepositoryConfig struct {
+	name          string
+	keyURL        string
+	fingerprint   string
+	uris          []string
+	suites        []string
+	components    []string
+	architectures []string
+	tmpDir        string
+	isSudo        bool
 }
 
-type NeovimLSPTask struct {
+// KeyPath returns keyring location, .asc is used for armored keys
+// since apt accepts both formats in Signed-By.
+func (c AptRepositoryConfig) KeyPath(isArmored bool) string {
+	ext := ".gpg"
+	if isArmored {
+		ext = ".asc"
+	}
+	return filepath.Join(AptKeyringsDir, c.name+ext)
+}
+
+func (c AptRepositoryConfig) SourcesPath() string {
+	return filepath.Join(AptSourcesDir, c.name+".sources")
+}
+
+// Deb822 renders repository definition in deb822 format.
+func (c AptRepositoryConfig) Deb822(signedBy string) string {
+	var b strings.Builder
+	b.WriteString("Types: deb\n")
+	b.WriteString("URIs: " + strings.Join(c.uris, " ") + "\n")
+	b.WriteString("Suites: " + strings.Join(c.suites, " ") + "\n")
+	if len(c.components) > 0 {
+		b.WriteString("Components: " + strings.Join(c.components, " ") + "\n")
+	}
+	if len(c.architectures) > 0 {
+		b.WriteString("Architectures: " + strings.Join(c.architectures, " ") + "\n")
+	}
+	b.WriteString("Signed-By: " + signedBy + "\n")
+	return b.String()
+}
+
+type AptRepositoryTask struct {
 	BaseTask
 	th TaskHelper
 	vh ValidationHelper
 }
 
-func (t *NeovimLSPTask) Validate() error {
+func (t *AptRepositoryTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-	cfg, _ := t.Config.(NeovimLSPConfig)
 
-	if err := t.vh.ValidatePath(cfg.path.path, true); err != nil {
-		return fmt.Errorf("%s: validation failed, %s", t.Name, err.Error())
+	cfg, _ := t.Config.(AptRepositoryConfig)
+
+	if len(cfg.name) == 0 || strings.ContainsAny(cfg.name, "/ ") {
+		return FPrefixError(t.Name, "repository name must be non-empty and contain no slashes or spaces")
 	}
-	if err := t.vh.ValidateURL(cfg.url); err != nil {
+	if err := t.vh.ValidateURL(cfg.keyURL); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidateFingerprint(cfg.fingerprint); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(cfg.uris) == 0 {
+		return FPrefixError(t.Name, "no repository uris are specified")
+	}
+	for _, uri := range cfg.uris {
+		if err := t.vh.ValidateURL(uri); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if len(cfg.suites) == 0 {
+		return FPrefixError(t.Name, "no repository suites are specified")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
 	return nil
 }
 
-func (t *NeovimLSPTask) Run() error {
-	cfg, _ := t.Config.(NeovimLSPConfig)
-	dstPath := cfg.path.Join()
+func (t *AptRepositoryTask) Run() error {
+	cfg, _ := t.Config.(AptRepositoryConfig)
+	tmpKey := filepath.Join(cfg.tmpDir, cfg.name+".key")
 
-	if err := t.th.GitClone(cfg.url, dstPath, cfg.isSudo); err != nil {
-		return fmt.Errorf("failed to clone git repository: %v", err)
+	if err := t.th.Download(cfg.keyURL, tmpKey, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	fingerprints, err := t.th.KeyFingerprints(tmpKey)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if !slices.Contains(fingerprints, FNormalizeFingerprint(cfg.fingerprint)) {
+		return FPrefixError(t.Name, fmt.Sprintf("key fingerprint mismatch, expected %s, got %v", cfg.fingerprint, fingerprints))
+	}
+
+	content, err := os.ReadFile(tmpKey)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	keyPath := cfg.KeyPath(bytes.HasPrefix(content, []byte("-----BEGIN PGP")))
+
+	if err := t.th.CreateDir(AptKeyringsDir, cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, path := range []string{keyPath, cfg.SourcesPath()} {
+		if err := t.Journal.Backup().Snapshot(path, cfg.isSudo); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if err := t.th.WriteFile(keyPath, string(content), "0644", cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.th.WriteFile(cfg.SourcesPath(), cfg.Deb822(keyPath), "0644", cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionAptRepository, Target: cfg.name, IsSudo: cfg.isSudo}
+	if err := t.Journal.Record(entry); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.th.AptUpdate(cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
 	}
 	return nil
 }
 
-type OhMyZshConfig struct {
-	tmpDir   string
-	path     Path
-	username string
-	url      string
-	isSudo   bool
+// Uninstall removes keyring and sources file written by Run
+// and refreshes package index.
+func (t *AptRepositoryTask) Uninstall() error {
+	cfg, _ := t.Config.(AptRepositoryConfig)
+
+	for _, path := range []string{cfg.SourcesPath(), cfg.KeyPath(true), cfg.KeyPath(false)} {
+		if err := t.th.DeletePath(path, cfg.isSudo); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if err := t.th.AptUpdate(cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	return nil
 }
 
-type OhMyZshTask struct {
+type PackagePreflightConfig struct {
+	// packages maps package name to downloaded .deb path,
+	// empty path means package is installed from package index.
+	packages map[string]string
+}
+
+// PackagePreflightTask checks that every package can be installed
+// before anything is installed, so typos are caught early.
+type PackagePreflightTask struct {
 	BaseTask
 	th TaskHelper
 	vh ValidationHelper
 }
 
-func (t *OhMyZshTask) Validate() error {
+func (t *PackagePreflightTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
 
-	cfg, _ := t.Config.(OhMyZshConfig)
+	cfg, _ := t.Config.(PackagePreflightConfig)
 
-	if len(cfg.username) == 0 {
-		return FPrefixError(t.Name, "empty username value")
-	}
-	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if err := t.vh.ValidateURL(cfg.url); err != nil {
-		return FPrefixError(t.Name, err.Error())
+	for name, path := range cfg.packages {
+		if len(name) == 0 {
+			return FPrefixError(t.Name, "package name cannot be empty")
+		}
+		if err := t.vh.ValidatePath(path, false); len(path) > 0 && err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
 	}
-
 	return nil
 }
 
-func (t *OhMyZshTask) Run() error {
-	cfg, _ := t.Config.(OhMyZshConfig)
-	scriptPath := filepath.Join(cfg.tmpDir, "install.sh")
+func (t *PackagePreflightTask) Run() error {
+	cfg, _ := t.Config.(PackagePreflightConfig)
 
-	if err := t.th.Download(cfg.url, scriptPath, cfg.isSudo); err != nil {
-		return fmt.Errorf("%v", err)
-	}
-	if _, err := FRunCommand("/bin/sh", []string{scriptPath}, false); err != nil {
-		return err
+	names := make([]string, 0, len(cfg.packages))
+	for name := range cfg.packages {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	var index []string
+	var errs []error
+	for _, name := range names {
+		path := cfg.packages[name]
+		if len(path) > 0 {
+			pkgName, err := t.th.DebField(path, "Package")
+			if err != nil {
+				errs = append(errs, err)
+			} else if pkgName != name {
+				errs = append(errs, fmt.Errorf("package '%s': %s declares package '%s'", name, filepath.Base(path), pkgName))
+			}
+			continue
+		}
 
-	if _, err := FRunCommand("/usr/bin/chsh", []string{cfg.username, "-s", "/bin/zsh"}, true); err != nil {
-		return fmt.Errorf("failed to change shell: %v", err)
+		if _, ok, err := t.th.PackageCandidate(name); err != nil {
+			errs = append(errs, err)
+			continue
+		} else if ok {
+			continue
+		}
+
+		if index == nil {
+			var err error
+			if index, err = t.th.PackageNames(); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		msg := fmt.Sprintf("package '%s' is not found in package index", name)
+		if suggestions := FSuggest(name, index, 3); len(suggestions) > 0 {
+			msg += fmt.Sprintf(", did you mean: %s?", strings.Join(suggestions, ", "))
+		}
+		errs = append(errs, errors.New(msg))
 	}
 
+	if len(errs) > 0 {
+		return FPrefixError(t.Name, errors.Join(errs...).Error())
+	}
 	return nil
 }
 
-type InstallNeovimConfig struct {
-	path    Path
-	shrc    ShrcConfig
-	tarPath string
-	isSudo  bool
+type OhMyZshConfig struct {
+	tmpDir   string
+	path     Path
+	username string
+	// repoURL is cloned instead of running install.sh, so zsh is not
+	// started and .zshrc is kept, Oh My Zsh is then loaded from a managed block.
+	// repoURL is checked out at revision, full commit hash, which is then locked.
+	repoURL  string
+	revision string
+	theme    string
+	plugins  []string
+	custom   []ZshCustom
+	// fpath are completion directories, e.g. ~/.local/share/zsh/site-functions.
+	fpath  []string
+	lock   *Lockfile
+	isSudo bool
 }
 
-type InstallNeovimTask struct {
+type OhMyZshTask struct {
 	BaseTask
 	th TaskHelper
 	vh ValidationHelper
 }
 
-func (t *InstallNeovimTask) Validate() error {
+func (t *OhMyZshTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
 
-	cfg, _ := t.Config.(InstallNeovimConfig)
-	if err := t.vh.ValidatePath(cfg.path.path, true); err != nil {
+	cfg, _ := t.Config.(OhMyZshConfig)
+
+	if len(cfg.username) == 0 {
+		return FPrefixError(t.Name, "empty username value")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-	if err := t.vh.ValidatePath(cfg.tarPath, false); err != nil {
+	if err := t.vh.ValidateURL(cfg.repoURL); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-	if err := t.vh.ValidatePath(cfg.shrc.path, false); err != nil {
+	if !FIsCommitHash(cfg.revision) {
+		return FPrefixError(t.Name, fmt.Sprintf("oh my zsh revision '%s' must be a full commit hash", cfg.revision))
+	}
+	if len(cfg.theme) == 0 {
+		return FPrefixError(t.Name, "zsh theme is empty")
+	}
+	if err := FValidateZshPlugins(cfg.plugins); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+	for _, c := range cfg.custom {
+		if err := FValidateZshPlugins([]string{c.name}); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if err := t.vh.ValidateURL(c.url); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
 
 	return nil
 }
 
-func (t *InstallNeovimTask) Run() error {
-	cfg, _ := t.Config.(InstallNeovimConfig)
-	dstPath := cfg.path.path
+func (t *OhMyZshTask) Run() error {
+	cfg, _ := t.Config.(OhMyZshConfig)
+
+	// install.sh is not run, checkout and .zshrc block are all it sets up,
+	// so install is repeatable and pinned
+	if err := t.configure(cfg); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.checkout(cfg); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
 
-	if err := t.th.ExtractTar(cfg.tarPath, dstPath, cfg.isSudo); err != nil {
+	prevShell, err := t.th.LoginShell(cfg.username)
+	if err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-	if err := t.th.AppendContent(cfg.shrc.path, cfg.shrc.content); err != nil {
+	if err := t.th.ChangeShell(cfg.username, "/bin/zsh", true); err != nil {
+		return err
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionShell, Target: cfg.username, Data: prevShell, IsSudo: true}
+	if err := t.Journal.Record(entry); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
 
 	return nil
 }
 
+// configure writes managed block, which loads Oh My Zsh with theme and plugins.
+// Existing .zshrc is kept instead of Oh My Zsh template.
+func (t *OhMyZshTask) configure(cfg OhMyZshConfig) error {
+	zshrc := filepath.Join(cfg.path.path, ".zshrc")
+	if err := t.Journal.Backup().Snapshot(zshrc, false); err != nil {
+		return err
+	}
+	block := ManagedBlock{
+		id:      "ohmyzsh",
+		content: FRenderOhMyZsh(cfg.path.Join(), cfg.theme, cfg.plugins, cfg.fpath),
+	}
+	if err := t.th.WriteBlock(zshrc, block); err != nil {
+		return err
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionBlock, Target: zshrc, Data: block.id}
+	return t.Journal.Record(entry)
+}
+
+// checkout clones Oh My Zsh at locked revision and custom plugins and themes.
+func (t *OhMyZshTask) checkout(cfg OhMyZshConfig) error {
+	_, err := os.Stat(filepath.Join(cfg.path.Join(), ".git"))
+	isNew := err != nil
+	opts := GitCloneOptions{ref: cfg.revision, ensure: true}
+	if _, err := t.th.GitLockedClone(cfg.repoURL, cfg.path.Join(), "ohmyzsh", opts, cfg.lock, false); err != nil {
+		return err
+	}
+	if isNew {
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: cfg.path.Join()}
+		if err := t.Journal.Record(entry); err != nil {
+			return err
+		}
+	}
+	customDir := filepath.Join(cfg.path.Join(), "custom")
+	for _, c := range cfg.custom {
+		dir := c.Dir(customDir)
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		isNew := err != nil
+		opts := GitCloneOptions{ref: c.ref, ensure: true}
+		if _, err := t.th.GitLockedClone(c.url, dir, "ohmyzsh-"+c.name, opts, cfg.lock, false); err != nil {
+			return fmt.Errorf("failed to clone %s: %v", c.name, err)
+		}
+		if isNew {
+			entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dir}
+			if err := t.Journal.Record(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return cfg.lock.Save(false)
+}
+
 type NeovimDotConfig struct {
-	path     Path
-	url      string
-	tmpDir   string
-	subpaths []string
-	isSudo   bool
+	path   Path
+	url    string
+	tmpDir string
+	// repoSubdir is the directory inside of repository which holds subpaths.
+	repoSubdir string
+	subpaths   []string
+	mode       DotMode
+	// repoDir is permanent checkout used in link mode.
+	repoDir    string
+	onConflict DotConflict
+	lock       *Lockfile
+	isSudo     bool
 }
 
 type NeovimDotTask struct {
@@ -241,12 +517,21 @@ func (t *NeovimDotTask) Validate() error {
 	if err := t.vh.ValidatePath(cfg.path.path, true); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
 	if err := t.vh.ValidateURL(cfg.url); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+	switch cfg.mode {
+	case DotLink:
+		if len(cfg.repoDir) == 0 {
+			return FPrefixError(t.Name, "repository directory is required in link mode")
+		}
+	case "", DotMove:
+		if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	default:
+		return FPrefixError(t.Name, fmt.Sprintf("unknown deploy mode '%s'", cfg.mode))
+	}
 	return nil
 }
 
@@ -254,7 +539,19 @@ func (t *NeovimDotTask) Run() error {
 	cfg, _ := t.Config.(NeovimDotConfig)
 	dstPath := cfg.path.Join()
 
-	if err := t.th.GitClone(cfg.url, cfg.tmpDir, cfg.isSudo); err != nil {
+	if cfg.mode == DotLink {
+		if err := t.runLink(cfg); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		return nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(cfg.url), ".git")
+	opts := GitCloneOptions{submodules: true, ensure: true}
+	if _, err := t.th.GitLockedClone(cfg.url, cfg.tmpDir, name, opts, cfg.lock, cfg.isSudo); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := cfg.lock.Save(false); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
   if err := FCreateDir(dstPath); err != nil {
@@ -262,11 +559,18 @@ func (t *NeovimDotTask) Run() error {
   }
 
 	for _, subpath := range cfg.subpaths {
-		src := filepath.Join(cfg.tmpDir, "nvim", subpath)
+		src := filepath.Join(cfg.tmpDir, cfg.repoSubdir, subpath)
 		dst := filepath.Join(dstPath, subpath)
+		if err := t.Journal.Backup().Snapshot(dst, cfg.isSudo); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
 		if err := t.th.Move(src, dst, cfg.isSudo); err != nil {
 			return FPrefixError(t.Name, err.Error())
 		}
+		entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dst, IsSudo: cfg.isSudo}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
 	}
 	return nil
 }
@@ -311,192 +615,244 @@ func (t *DownloadTask) Run() error {
 	return nil
 }
 
-type InstallGolangConfig struct {
-	path    Path
-	shrc    ShrcConfig
-	tarPath string
-	isSudo  bool
-}
-
 type Path struct {
 	path    string
 	subpath string
 }
 
+// ShrcConfig declares shell environment, which is written as managed block
+// into startup file of every shell. Shells are detected if none are listed.
 type ShrcConfig struct {
-	path    string
-	content string
+	homePath string
+	id       string
+	shells   []Shell
+	env      []EnvChange
 }
 
 func (p *Path) Join() string {
 	return filepath.Join(p.path, p.subpath)
 }
 
-type InstallGolangTask struct {
+type DeletePathConfig struct {
+	path   string
+	isSudo bool
+}
+
+type DeletePathTask struct {
 	BaseTask
 	th TaskHelper
 	vh ValidationHelper
 }
 
-func (t InstallGolangTask) Validate() error {
+func (t *DeletePathTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
 
-	cfg, _ := t.Config.(InstallGolangConfig)
+	cfg, _ := t.Config.(DeletePathConfig)
 
-	if err := t.vh.ValidatePath(cfg.path.path, true); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if err := t.vh.ValidatePath(cfg.tarPath, false); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if err := t.vh.ValidatePath(cfg.shrc.path, false); err != nil {
+	if err := t.vh.ValidatePath(cfg.path, false); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+
 	return nil
 }
 
-func (t InstallGolangTask) Run() error {
-	cfg, _ := t.Config.(InstallGolangConfig)
-	dstPath := cfg.path.path
-
-	if err := t.th.ExtractTar(cfg.tarPath, dstPath, cfg.isSudo); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if _, err := FRunCommand(filepath.Join(dstPath, "go/bin/go"), []string{"install", "golang.org/x/tools/gopls@latest"}, false); err != nil {
+func (t DeletePathTask) Run() error {
+	cfg, _ := t.Config.(DeletePathConfig)
+	if err := t.Journal.Backup().Snapshot(cfg.path, cfg.isSudo); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-	if err := t.th.AppendContent(cfg.shrc.path, cfg.shrc.content); err != nil {
+	if err := t.th.DeletePath(cfg.path, cfg.isSudo); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
-
 	return nil
 }
 
-type InstallTypescriptConfig struct {
-	version        string
-	installNVMPath string
-	homePath       string
-	shrc           ShrcConfig
-	isSudo         bool
+type DirectoryPromptConfig struct {
+	path   string
+	isSudo bool
+	action func(bool) error
 }
-
-type InstallTypescriptTask struct {
+type DirectoryPromptTask struct {
 	BaseTask
-	th TaskHelper
 	vh ValidationHelper
+	th TaskHelper
 }
 
-func (t InstallTypescriptTask) Validate() error {
+func (t *DirectoryPromptTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
 
-	cfg, _ := t.Config.(InstallTypescriptConfig)
+	return nil
+}
 
-	if err := t.vh.ValidatePath(cfg.installNVMPath, false); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if err := t.vh.ValidatePath(cfg.homePath, false); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if err := t.vh.ValidatePath(cfg.shrc.path, false); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if len(cfg.version) == 0 {
-		return FPrefixError(t.Name, "no version is specified")
+func (t *DirectoryPromptTask) Run() error {
+	cfg, _ := t.Config.(DirectoryPromptConfig)
+	_, err := os.Stat(cfg.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		slog.Error(err.Error(), "task_name", t.Name)
+		return err
 	}
 
-	return nil
-}
+	ask := fmt.Sprintf("Directory '%s' is not empty. Do you want to overwrite it? (y/n): ", cfg.path)
 
-func (t InstallTypescriptTask) Run() error {
-	cfg, _ := t.Config.(InstallTypescriptConfig)
+	t.th.PromptAction(ask, cfg.action)
 
-	if err := t.th.UpdatePermission(cfg.installNVMPath, "u+x", cfg.isSudo); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if _, err := FRunCommand("/bin/zsh", []string{"-c", cfg.installNVMPath}, cfg.isSudo); err != nil {
-		return FPrefixError(t.Name, "failed to install nvm")
-	}
-	if _, err := FRunCommand("/bin/zsh", []string{"-c", fmt.Sprintf("source %s/.nvm/nvm.sh && nvm install %s", cfg.homePath, cfg.version)}, false); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if _, err := FRunCommand("/bin/zsh", []string{"-c", fmt.Sprintf("source %s/.nvm/nvm.sh && %s/.nvm/versions/node/v%s/bin/npm install -g typescript-language-server typescript", cfg.homePath, cfg.homePath, cfg.version)}, false); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
-	if err := t.th.AppendContent(cfg.shrc.path, cfg.shrc.content); err != nil {
-		return FPrefixError(t.Name, err.Error())
-	}
 	return nil
 }
 
-type DeletePathConfig struct {
-	path   string
-	isSudo bool
+type UninstallConfig struct {
+	component     string
+	purgePackages bool
 }
 
-type DeletePathTask struct {
+// UninstallTask reverts journal entries in reverse order of recording.
+// Empty component reverts everything, packages are purged only if asked.
+type UninstallTask struct {
 	BaseTask
 	th TaskHelper
 	vh ValidationHelper
 }
 
-func (t *DeletePathTask) Validate() error {
+func (t *UninstallTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+	if t.Journal == nil {
+		return FPrefixError(t.Name, "journal is required to uninstall")
+	}
+	return nil
+}
 
-	cfg, _ := t.Config.(DeletePathConfig)
+func (t *UninstallTask) Run() error {
+	cfg, _ := t.Config.(UninstallConfig)
 
-	if err := t.vh.ValidatePath(cfg.path, false); err != nil {
+	entries, err := t.Journal.Entries()
+	if err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+	if len(cfg.component) > 0 && !slices.ContainsFunc(entries, func(e JournalEntry) bool { return e.Component == cfg.component }) {
+		return FPrefixError(t.Name, fmt.Sprintf("nothing is recorded for component '%s'", cfg.component))
+	}
 
+	kept := make([]bool, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if len(cfg.component) > 0 && e.Component != cfg.component {
+			kept[i] = true
+			continue
+		}
+		if e.Action == ActionPackage && !cfg.purgePackages {
+			kept[i] = true
+			continue
+		}
+		if err := t.revert(e); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		slog.Info("reverted", "task_name", t.Name, "component", e.Component, "action", e.Action, "target", e.Target)
+
+		remaining := []JournalEntry{}
+		for j, keep := range kept {
+			if j < i || keep {
+				remaining = append(remaining, entries[j])
+			}
+		}
+		if err := t.Journal.Rewrite(remaining); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
 	return nil
 }
 
-func (t DeletePathTask) Run() error {
-	cfg, _ := t.Config.(DeletePathConfig)
-	if err := t.th.DeletePath(cfg.path, cfg.isSudo); err != nil {
-		return FPrefixError(t.Name, err.Error())
+func (t *UninstallTask) revert(e JournalEntry) error {
+	switch e.Action {
+	case ActionPath, ActionContent, ActionBlock:
+		if err := t.Journal.Backup().Snapshot(e.Target, e.IsSudo); err != nil {
+			return err
+		}
 	}
-	return nil
+
+	switch e.Action {
+	case ActionPath:
+		return t.th.DeletePath(e.Target, e.IsSudo)
+	case ActionContent:
+		return t.th.RemoveContent(e.Target, e.Data)
+	case ActionBlock:
+		return t.th.RemoveBlock(e.Target, e.Data)
+	case ActionPackage:
+		return t.th.PurgePackage(e.Target, e.IsSudo)
+	case ActionShell:
+		return t.th.ChangeShell(e.Target, e.Data, e.IsSudo)
+	case ActionAptRepository:
+		repoTask := AptRepositoryTask{
+			BaseTask: BaseTask{
+				Name:   t.Name + " " + e.Target,
+				Config: AptRepositoryConfig{name: e.Target, isSudo: e.IsSudo},
+			},
+		}
+		return repoTask.Uninstall()
+	}
+	return fmt.Errorf("unknown journal action: %s", e.Action)
 }
 
-type DirectoryPromptConfig struct {
-	path   string
-	isSudo bool
-	action func(bool) error
+type RestoreConfig struct {
+	runID string
 }
-type DirectoryPromptTask struct {
+
+// RestoreTask puts back every snapshot taken during given run,
+// in reverse order of snapshots.
+type RestoreTask struct {
 	BaseTask
-	vh ValidationHelper
 	th TaskHelper
+	vh ValidationHelper
 }
 
-func (t *DirectoryPromptTask) Validate() error {
+func (t *RestoreTask) Validate() error {
 	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
 		return FPrefixError(t.Name, err.Error())
 	}
+	if t.Journal == nil {
+		return FPrefixError(t.Name, "journal is required to restore")
+	}
 
-	return nil
-}
+	cfg, _ := t.Config.(RestoreConfig)
 
-func (t *DirectoryPromptTask) Run() error {
-	cfg, _ := t.Config.(DirectoryPromptConfig)
-	_, err := os.Stat(cfg.path)
-	if os.IsNotExist(err) {
-		return nil
-	} else if err != nil {
-		slog.Error(err.Error(), "task_name", t.Name)
-		return err
+	if len(cfg.runID) == 0 || strings.ContainsAny(cfg.runID, "/.") {
+		return FPrefixError(t.Name, "invalid run id")
 	}
+	return nil
+}
 
-	ask := fmt.Sprintf("Directory '%s' is not empty. Do you want to overwrite it? (y/n): ", cfg.path)
+func (t *RestoreTask) Run() error {
+	cfg, _ := t.Config.(RestoreConfig)
+	backup := t.Journal.BackupOf(cfg.runID)
 
-	t.th.PromptAction(ask, cfg.action)
+	entries, err := backup.Entries()
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(entries) == 0 {
+		return FPrefixError(t.Name, fmt.Sprintf("no backup is found for run '%s'", cfg.runID))
+	}
 
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if err := t.th.DeletePath(e.Path, e.IsSudo); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if len(e.Name) > 0 {
+			if err := t.th.CreateDir(filepath.Dir(e.Path), e.IsSudo); err != nil {
+				return FPrefixError(t.Name, err.Error())
+			}
+			if err := t.th.Copy(filepath.Join(backup.Dir(), e.Name), e.Path, e.IsSudo); err != nil {
+				return FPrefixError(t.Name, err.Error())
+			}
+		}
+		slog.Info("restored", "task_name", t.Name, "path", e.Path)
+	}
 	return nil
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRemote is a bare repository with one commit, pushed from work clone.
+type gitRemote struct {
+	remote string
+	work   string
+}
+
+// newGitRemote creates gitRemote name.git in temp dir, with git isolated
+// from user and system configuration.
+func newGitRemote(t *testing.T, name string) gitRemote {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "autonvim")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "autonvim@localhost")
+	}
+
+	r := gitRemote{
+		remote: filepath.Join(dir, name+".git"),
+		work:   filepath.Join(dir, "work"),
+	}
+	git(t, "", "init", "--quiet", "--bare", "--initial-branch=main", r.remote)
+	git(t, "", "clone", "--quiet", r.remote, r.work)
+	writeFile(t, filepath.Join(r.work, "init.lua"), "-- init\n")
+	r.commit(t, "init")
+	return r
+}
+
+// commit commits every change in work clone and pushes it.
+func (r gitRemote) commit(t *testing.T, message string) string {
+	t.Helper()
+	git(t, r.work, "add", "--all")
+	git(t, r.work, "commit", "--quiet", "-m", message)
+	git(t, r.work, "push", "--quiet", "origin", "HEAD:main")
+	return git(t, r.work, "rev-parse", "HEAD")
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	if len(dir) > 0 {
+		args = append([]string{"-C", dir}, args...)
+	}
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ToolchainSpec declares how a tool is fetched, laid out and exposed.
+// In url, checksumURL, env, postInstall and probe {version} is replaced
+// with version. In env, postInstall and probe {dir} is replaced with
+// the install directory.
+type ToolchainSpec struct {
+	name    string
+	version string
+	// url is .tar.*, .zip archive or a single binary.
+	url string
+	// sha256 is expected digest of the artifact, or checksumURL
+	// is sha256sum formatted file listing it, e.g. SHASUMS256.txt.
+	sha256      string
+	checksumURL string
+	// strip removes leading components of tar archive paths,
+	// usually 1 for archives with top level directory.
+	strip int
+	// binDir is binaries directory inside of install directory, it is put on PATH.
+	binDir string
+	env    []EnvChange
+	// postInstall are shell commands run with binDir on PATH.
+	postInstall []string
+	// probe prints version, its output must contain version.
+	probe []string
+}
+
+func (s ToolchainSpec) expand(value, dir string) string {
+	return strings.NewReplacer("{version}", s.version, "{dir}", dir).Replace(value)
+}
+
+type ToolchainConfig struct {
+	// root keeps every tool in its own directory, versions are installed
+	// side by side and current symlink points to the active one.
+	root   string
+	spec   ToolchainSpec
+	tmpDir string
+	// shrc receives PATH and spec env in addition to its own env,
+	// empty id keeps toolchain out of shell environment.
+	shrc ShrcConfig
+}
+
+// VersionDir returns install directory of spec version.
+func (c ToolchainConfig) VersionDir() string {
+	return filepath.Join(c.root, c.spec.name, c.spec.version)
+}
+
+// CurrentDir returns symlink of the active version.
+func (c ToolchainConfig) CurrentDir() string {
+	return filepath.Join(c.root, c.spec.name, "current")
+}
+
+// ToolchainTask installs verified tool release into versioned layout,
+// runs post install commands and exposes it in shell environment.
+type ToolchainTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *ToolchainTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	cfg, _ := t.Config.(ToolchainConfig)
+	spec := cfg.spec
+
+	if len(cfg.root) == 0 {
+		return FPrefixError(t.Name, "toolchain root is empty")
+	}
+	if len(spec.name) == 0 || len(spec.version) == 0 || strings.ContainsAny(spec.name+spec.version, "/ ") {
+		return FPrefixError(t.Name, fmt.Sprintf("invalid toolchain name '%s' or version '%s'", spec.name, spec.version))
+	}
+	if err := t.vh.ValidateURL(spec.expand(spec.url, "")); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if (len(spec.sha256) == 0) == (len(spec.checksumURL) == 0) {
+		return FPrefixError(t.Name, "either sha256 or checksum url is required")
+	}
+	if len(spec.checksumURL) > 0 {
+		if err := t.vh.ValidateURL(spec.expand(spec.checksumURL, "")); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	if strings.HasSuffix(spec.url, ".zip") && spec.strip > 0 {
+		return FPrefixError(t.Name, "strip is not supported for zip archives")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if len(cfg.shrc.id) > 0 {
+		if err := t.vh.ValidateShrc(cfg.shrc); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+func (t *ToolchainTask) Run() error {
+	cfg, _ := t.Config.(ToolchainConfig)
+	spec := cfg.spec
+	versionDir := cfg.VersionDir()
+
+	isNewRoot, err := t.th.IsPathEmpty(filepath.Join(cfg.root, spec.name))
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	isNewVersion, err := t.th.IsPathEmpty(versionDir)
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if !isNewVersion {
+		slog.Info("toolchain is already installed", "task_name", t.Name, "name", spec.name, "version", spec.version)
+	} else if err := t.install(cfg); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.th.SwitchCurrent(filepath.Join(cfg.root, spec.name), versionDir, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	target := versionDir
+	if isNewRoot {
+		target = filepath.Join(cfg.root, spec.name)
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: target}
+	if err := t.Journal.Record(entry); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	pathEnv := "PATH=" + filepath.Join(versionDir, spec.binDir) + ":" + os.Getenv("PATH")
+	for _, command := range spec.postInstall {
+		args := []string{pathEnv, "/bin/sh", "-c", spec.expand(command, versionDir)}
+		if _, err := FRunCommand("env", args, false); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("post install command failed: %v", err))
+		}
+	}
+	if len(spec.probe) > 0 {
+		args := []string{pathEnv}
+		for _, arg := range spec.probe {
+			args = append(args, spec.expand(arg, versionDir))
+		}
+		out, _, err := FRunCommandCombined("env", args, false)
+		if err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("version probe failed: %v\n%s", err, out))
+		}
+		if !strings.Contains(out, spec.version) {
+			return FPrefixError(t.Name, fmt.Sprintf("version probe does not report %s:\n%s", spec.version, out))
+		}
+	}
+
+	if len(cfg.shrc.id) == 0 {
+		return nil
+	}
+	shrc := cfg.shrc
+	env := []EnvChange{{kind: EnvPath, value: filepath.Join(cfg.CurrentDir(), spec.binDir)}}
+	for _, e := range spec.env {
+		e.value = spec.expand(e.value, cfg.CurrentDir())
+		env = append(env, e)
+	}
+	shrc.env = append(env, shrc.env...)
+	files, err := t.th.WriteShellEnv(shrc, t.Journal.Backup())
+	if err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	for _, file := range files {
+		entry = JournalEntry{Task: t.Name, Action: ActionBlock, Target: file, Data: shrc.id}
+		if err := t.Journal.Record(entry); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// install downloads and verifies artifact and lays it out in version directory.
+func (t *ToolchainTask) install(cfg ToolchainConfig) error {
+	spec := cfg.spec
+	url := spec.expand(spec.url, "")
+	artifact := filepath.Base(url)
+	artifactPath := filepath.Join(cfg.tmpDir, spec.name+"-"+artifact)
+	if err := t.th.Download(url, artifactPath, false); err != nil {
+		return err
+	}
+
+	expected := strings.ToLower(spec.sha256)
+	if len(spec.checksumURL) > 0 {
+		sumsPath := artifactPath + ".sha256"
+		if err := t.th.Download(spec.expand(spec.checksumURL, ""), sumsPath, false); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(sumsPath)
+		if err != nil {
+			return fmt.Errorf("failed to read checksums: %v", err)
+		}
+		var ok bool
+		if expected, ok = FParseChecksums(string(data))[artifact]; !ok {
+			return fmt.Errorf("checksum file does not list %s", artifact)
+		}
+	}
+	digest, err := t.th.FileSHA256(artifactPath)
+	if err != nil {
+		return err
+	}
+	if digest != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", artifact, expected, digest)
+	}
+
+	versionDir := cfg.VersionDir()
+	if err := FCreateDir(versionDir); err != nil {
+		return err
+	}
+	switch {
+	case strings.Contains(artifact, ".tar."), strings.HasSuffix(artifact, ".tgz"):
+		err = t.th.ExtractArchive(artifactPath, versionDir, spec.strip, false)
+	case strings.HasSuffix(artifact, ".zip"):
+		err = t.th.ExtractZip(artifactPath, versionDir, false)
+	default:
+		binDir := filepath.Join(versionDir, spec.binDir)
+		if err = FCreateDir(binDir); err == nil {
+			err = t.th.Copy(artifactPath, filepath.Join(binDir, spec.name), false)
+		}
+		if err == nil {
+			err = t.th.UpdatePermission(filepath.Join(binDir, spec.name), "0755", false)
+		}
+	}
+	if err != nil {
+		if err := t.th.DeletePath(versionDir, false); err != nil {
+			slog.Error(err.Error())
+		}
+		return err
+	}
+	slog.Info("toolchain installed", "task_name", t.Name, "name", spec.name, "version", spec.version, "path", versionDir)
+	return nil
+}
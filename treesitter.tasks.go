@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ParserSpec declares tree-sitter grammar compiled into parser/<lang>.so.
+type ParserSpec struct {
+	lang string
+	url  string
+	// revision is a tag or commit, parsers are always built from pinned sources.
+	revision string
+	// location is grammar directory inside of repository,
+	// for repositories with several grammars such as tree-sitter-typescript.
+	location string
+}
+
+type TreesitterConfig struct {
+	// path is parser directory on Neovim runtime path,
+	// such as ~/.local/share/nvim/site/parser.
+	path     string
+	parsers  []ParserSpec
+	tmpDir   string
+	compiler string
+	nvimPath string
+}
+
+// TreesitterTask compiles tree-sitter parsers with system C compiler
+// and checks that Neovim is able to load them.
+type TreesitterTask struct {
+	BaseTask
+	th TaskHelper
+	vh ValidationHelper
+}
+
+func (t *TreesitterTask) Validate() error {
+	if err := t.vh.ValidateBaseTask(t.BaseTask, t.Config); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+
+	cfg, _ := t.Config.(TreesitterConfig)
+
+	if len(cfg.path) == 0 {
+		return FPrefixError(t.Name, "parser path is empty")
+	}
+	if err := t.vh.ValidatePath(cfg.tmpDir, true); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if err := t.vh.ValidatePath(cfg.nvimPath, false); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	if _, err := exec.LookPath(cfg.compiler); err != nil {
+		return FPrefixError(t.Name, fmt.Sprintf("compiler %s is not found, is build-essential installed? %v", cfg.compiler, err))
+	}
+	revisions := make(map[string]string, len(cfg.parsers))
+	for _, p := range cfg.parsers {
+		if len(p.lang) == 0 || strings.ContainsAny(p.lang, "/. ") {
+			return FPrefixError(t.Name, fmt.Sprintf("invalid parser language '%s'", p.lang))
+		}
+		if err := t.vh.ValidateURL(p.url); err != nil {
+			return FPrefixError(t.Name, err.Error())
+		}
+		if len(p.revision) == 0 {
+			return FPrefixError(t.Name, fmt.Sprintf("parser '%s' has no pinned revision", p.lang))
+		}
+		// parsers of one repository are built from a single checkout
+		if rev, ok := revisions[p.url]; ok && rev != p.revision {
+			return FPrefixError(t.Name, fmt.Sprintf("parsers of %s are pinned to different revisions %s and %s", p.url, rev, p.revision))
+		}
+		revisions[p.url] = p.revision
+	}
+	return nil
+}
+
+func (t *TreesitterTask) Run() error {
+	cfg, _ := t.Config.(TreesitterConfig)
+
+	if err := FCreateDir(cfg.path); err != nil {
+		return FPrefixError(t.Name, err.Error())
+	}
+	// repository url to its checkout
+	checkouts := make(map[string]string, len(cfg.parsers))
+	for _, p := range cfg.parsers {
+		repoDir, ok := checkouts[p.url]
+		if !ok {
+			repoDir = filepath.Join(cfg.tmpDir, "tree-sitter-"+p.lang)
+			opts := GitCloneOptions{ref: p.revision, depth: 1, ensure: true}
+			if err := t.th.GitClone(p.url, repoDir, opts, false); err != nil {
+				return FPrefixError(t.Name, fmt.Sprintf("parser %s: %v", p.lang, err))
+			}
+			checkouts[p.url] = repoDir
+		}
+		if err := t.build(cfg, p, repoDir); err != nil {
+			return FPrefixError(t.Name, fmt.Sprintf("parser %s: %v", p.lang, err))
+		}
+	}
+	return nil
+}
+
+// build compiles parser from repository checkout and installs it,
+// once Neovim has loaded it.
+func (t *TreesitterTask) build(cfg TreesitterConfig, p ParserSpec, repoDir string) error {
+	srcDir := filepath.Join(repoDir, p.location, "src")
+	tmpOut := filepath.Join(cfg.tmpDir, p.lang+".so")
+	args := []string{"-o", tmpOut, "-I", srcDir, "-shared", "-fPIC", "-Os", filepath.Join(srcDir, "parser.c")}
+	if _, err := os.Stat(filepath.Join(srcDir, "scanner.c")); err == nil {
+		args = append(args, filepath.Join(srcDir, "scanner.c"))
+	} else if _, err := os.Stat(filepath.Join(srcDir, "scanner.cc")); err == nil {
+		args = append(args, "-x", "c++", filepath.Join(srcDir, "scanner.cc"), "-x", "none", "-lstdc++")
+	}
+	if _, err := FRunCommand(cfg.compiler, args, false); err != nil {
+		return fmt.Errorf("failed to compile: %v", err)
+	}
+
+	probe := fmt.Sprintf(
+		"lua local ok, res, err = pcall(vim.treesitter.language.add, %q, { path = %q }); "+
+			"if not ok or (res == nil and err ~= nil) then io.stderr:write(tostring(err or res)); vim.cmd('cquit 1') end",
+		p.lang, tmpOut)
+	if out, _, err := FRunCommandCombined(cfg.nvimPath, []string{"--headless", "-u", "NONE", "-c", probe, "-c", "qa!"}, false); err != nil {
+		return fmt.Errorf("neovim failed to load parser: %v\n%s", err, out)
+	}
+
+	dst := filepath.Join(cfg.path, p.lang+".so")
+	if err := t.Journal.Backup().Snapshot(dst, false); err != nil {
+		return err
+	}
+	if err := t.th.Move(tmpOut, dst, false); err != nil {
+		return err
+	}
+	entry := JournalEntry{Task: t.Name, Action: ActionPath, Target: dst}
+	return t.Journal.Record(entry)
+}
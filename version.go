@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VersionConstraint describes requested package version,
+// using dpkg relation operators: <<, <=, =, >=, >>.
+type VersionConstraint struct {
+	op      string
+	version string
+}
+
+// IsEmpty reports whether constraint accepts any version.
+func (c VersionConstraint) IsEmpty() bool {
+	return len(c.version) == 0
+}
+
+// IsExact reports whether constraint pins single version,
+// which can be passed to apt as pkg=ver.
+func (c VersionConstraint) IsExact() bool {
+	return !c.IsEmpty() && c.op == "="
+}
+
+// Satisfies compares installed version against constraint.
+// Empty constraint is satisfied by any version.
+func (c VersionConstraint) Satisfies(installed string) bool {
+	if c.IsEmpty() {
+		return true
+	}
+	cmp := FCompareVersions(installed, c.version)
+	switch c.op {
+	case "<<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "=":
+		return cmp == 0
+	case ">=":
+		return cmp >= 0
+	case ">>":
+		return cmp > 0
+	}
+	return false
+}
+
+func (c VersionConstraint) String() string {
+	if c.IsEmpty() {
+		return "any"
+	}
+	return c.op + " " + c.version
+}
+
+// FParseVersionConstraint parses constraint such as ">= 1.2", "=1:2.3-1" or "2.3".
+// Version without operator is treated as exact match.
+// Empty input results in empty constraint.
+func FParseVersionConstraint(input string) (VersionConstraint, error) {
+	input = strings.TrimSpace(input)
+	if len(input) == 0 {
+		return VersionConstraint{}, nil
+	}
+
+	op := "="
+	for _, candidate := range []string{"<<", "<=", ">=", ">>", "="} {
+		if strings.HasPrefix(input, candidate) {
+			op = candidate
+			input = strings.TrimSpace(input[len(candidate):])
+			break
+		}
+	}
+	if len(input) == 0 {
+		return VersionConstraint{}, fmt.Errorf("version is missing after operator %s", op)
+	}
+	if strings.ContainsAny(input, " <>=") {
+		return VersionConstraint{}, fmt.Errorf("invalid version constraint: %s", input)
+	}
+	return VersionConstraint{op: op, version: input}, nil
+}
+
+// FCompareVersions compares two Debian package versions
+// in [epoch:]upstream[-revision] form, following dpkg ordering rules.
+// Returns negative number if a < b, 0 if a == b and positive number if a > b.
+func FCompareVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitVersion(a)
+	bEpoch, bUpstream, bRevision := splitVersion(b)
+
+	if aEpoch != bEpoch {
+		if aEpoch < bEpoch {
+			return -1
+		}
+		return 1
+	}
+	if cmp := compareVersionPart(aUpstream, bUpstream); cmp != 0 {
+		return cmp
+	}
+	return compareVersionPart(aRevision, bRevision)
+}
+
+func splitVersion(v string) (int, string, string) {
+	v = strings.TrimSpace(v)
+	epoch := 0
+	if idx := strings.IndexByte(v, ':'); idx != -1 {
+		for _, r := range v[:idx] {
+			if r < '0' || r > '9' {
+				break
+			}
+			epoch = epoch*10 + int(r-'0')
+		}
+		v = v[idx+1:]
+	}
+	revision := ""
+	if idx := strings.LastIndexByte(v, '-'); idx != -1 {
+		revision = v[idx+1:]
+		v = v[:idx]
+	}
+	return epoch, v, revision
+}
+
+// versionOrder assigns sort weight to non-digit character,
+// tilde sorts before everything, even the end of part.
+func versionOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c >= '0' && c <= '9':
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareVersionPart(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := 0, 0
+			if i < len(a) && !isDigit(a[i]) {
+				ac = versionOrder(a[i])
+			}
+			if j < len(b) && !isDigit(b[j]) {
+				bc = versionOrder(b[j])
+			}
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		diff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if diff == 0 {
+				diff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if diff != 0 {
+			return diff
+		}
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1:1.0", "2.0", 1},
+		{"0:1.0", "1.0", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-1", "1.0", 1},
+		{"1.10", "1.9", 1},
+		{"1.0a", "1.0", 1},
+		{"2.3.4-1ubuntu1", "2.3.4-1", 1},
+	}
+	for _, tt := range tests {
+		got := FCompareVersions(tt.a, tt.b)
+		if sign(got) != tt.want {
+			t.Errorf("FCompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if back := FCompareVersions(tt.b, tt.a); sign(back) != -tt.want {
+			t.Errorf("FCompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, back, -tt.want)
+		}
+	}
+}
+
+func TestParseVersionConstraint(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    VersionConstraint
+		wantErr bool
+	}{
+		{input: "", want: VersionConstraint{}},
+		{input: "2.3", want: VersionConstraint{op: "=", version: "2.3"}},
+		{input: "=1:2.3-1", want: VersionConstraint{op: "=", version: "1:2.3-1"}},
+		{input: ">= 1.2", want: VersionConstraint{op: ">=", version: "1.2"}},
+		{input: "<<1.0~rc1", want: VersionConstraint{op: "<<", version: "1.0~rc1"}},
+		{input: ">=", wantErr: true},
+		{input: "<< ", wantErr: true},
+		{input: "> 1.0", wantErr: true},
+		{input: ">= 1.0 2.0", wantErr: true},
+		{input: "1.0 <= 2.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := FParseVersionConstraint(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("FParseVersionConstraint(%q) = %v, want error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FParseVersionConstraint(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("FParseVersionConstraint(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestVersionConstraintSatisfies(t *testing.T) {
+	tests := []struct {
+		constraint string
+		installed  string
+		want       bool
+	}{
+		{"", "0.1", true},
+		{">= 1.0", "1:0.5", true},
+		{">= 1.0", "1.0~rc1", false},
+		{"<< 1.0", "1.0~rc1", true},
+		{"= 1.0-1", "1.0-2", false},
+		{">> 1.0-1", "1.0-2", true},
+	}
+	for _, tt := range tests {
+		c, err := FParseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Satisfies(tt.installed); got != tt.want {
+			t.Errorf("%q satisfies %q = %v, want %v", tt.installed, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}